@@ -15,15 +15,30 @@
 package connection
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"sync"
 
 	"github.com/dolthub/doltgresql/utils"
 )
 
-// TODO: determine how to handle messages that are larger than the buffer
-const bufferSize = 2048
+const (
+	// bufferSize is the size of the pooled buffers. Messages that are larger than this size are given their own buffer.
+	bufferSize = 2048
+	// headerSize is the size of the header and message length for messages that contain a header.
+	headerSize = 5
+	// lengthSize is the size of the message length that precedes the contents of every message.
+	lengthSize = 4
+	// maxMessageSize is the largest message that may be received from a client. This matches the largest allocation
+	// that PostgreSQL permits for a single message (1GB - 1).
+	maxMessageSize = 0x3fffffff
+	// maxStartupMessageSize is the largest message that may be received from a client that has not yet completed the
+	// startup phase. This matches the limit that PostgreSQL enforces on startup packets.
+	maxStartupMessageSize = 10000
+)
 
 // connBuffers maintains a pool of buffers, reusable between connections.
 var connBuffers = sync.Pool{
@@ -34,87 +49,67 @@ var connBuffers = sync.Pool{
 
 var sliceOfZeroes = make([]byte, bufferSize)
 
-// Receive returns all messages that were sent from the given connection. A connection may send multiple messages at
-// once, therefore this may return multiple messages. This checks with all messages that have a Header, and have
-// called AddMessageHeader within their init() function. Returns a nil slice if no messages were matched. This is the
-// recommended way to check for messages when a specific message is not expected. Use ReceiveInto or ReceiveIntoAny when
-// expecting specific messages, where it would be an error to receive messages different from the expectation.
-func Receive(conn net.Conn) ([]Message, error) {
-	buffer := connBuffers.Get().([]byte)
-	defer connBuffers.Put(zeroBuffer(buffer))
-
-	if _, err := conn.Read(buffer); err != nil {
+// Receive returns the next message that was sent from the given connection. Messages are framed by their header and
+// length, so a message may span any number of reads from the connection, and the connection is never read past the end
+// of the message. This checks with all messages that have a Header, and have called AddMessageHeader within their
+// init() function. This is the recommended way to check for messages when a specific message is not expected. Use
+// ReceiveInto or ReceiveIntoAny when expecting specific messages, where it would be an error to receive messages
+// different from the expectation.
+func Receive(conn net.Conn) (Message, error) {
+	buffer, release, err := readMessage(conn, true)
+	if err != nil {
 		return nil, err
 	}
-	db := newDecodeBuffer(buffer)
-	var outMessages []Message
-	for len(db.data) > 0 {
-		message, ok := allMessageHeaders[db.data[0]]
-		if !ok {
-			break
-		}
-		outMessage, err := receiveFromBuffer(db, message)
-		if err != nil {
-			return nil, err
-		}
-		outMessages = append(outMessages, outMessage)
-		db.next()
+	defer release()
+
+	message, ok := allMessageHeaders[buffer[0]]
+	if !ok {
+		return nil, fmt.Errorf("received a message with an unknown header: %q", buffer[0])
+	}
+	message, err = receiveFromBuffer(newDecodeBuffer(buffer), message)
+	if err != nil {
+		return nil, err
 	}
-	return outMessages, nil
+	recordMessage(conn, Direction_Frontend, message, buffer)
+	return message, nil
 }
 
 // ReceiveInto reads the given Message from the connection. This should only be used when a specific message is expected,
-// and that message did not call AddMessageHeader in its init() function. In addition, if the client sends multiple
-// messages at once, then this will only read the first message. If multiple messages are expected, use ReceiveIntoAny.
+// and that message did not call AddMessageHeader in its init() function. This includes messages that share their
+// header with other messages, such as the responses to an authentication request.
 func ReceiveInto[T Message](conn net.Conn, message T) (out T, err error) {
-	buffer := connBuffers.Get().([]byte)
-	defer connBuffers.Put(zeroBuffer(buffer))
+	buffer, release, err := readMessage(conn, hasHeader(message))
+	if err != nil {
+		return out, err
+	}
+	defer release()
 
-	if _, err := conn.Read(buffer); err != nil {
+	if out, err = receiveFromBuffer(newDecodeBuffer(buffer), message); err != nil {
 		return out, err
 	}
-	db := newDecodeBuffer(buffer)
-	outMessage, err := receiveFromBuffer(db, message)
-	return outMessage, err
+	recordMessage(conn, Direction_Frontend, out, buffer)
+	return out, nil
 }
 
-// ReceiveIntoAny returns all messages that were sent from the given connection. This should only be used when one of
-// several specific messages are expected, and those messages did not call AddMessageHeader in their init() functions.
-// Messages given first have a higher matching priority. Returns a nil slice if no messages matched. Only returns an
-// error on connection errors. This will not error if the connection sends extra data or unspecified messages.
-func ReceiveIntoAny(conn net.Conn, messages ...Message) ([]Message, error) {
-	buffer := connBuffers.Get().([]byte)
-	defer connBuffers.Put(zeroBuffer(buffer))
-
-	if _, err := conn.Read(buffer); err != nil {
-		return nil, err
+// ReceiveIntoAny reads the next message from the given connection, and returns the first of the given messages that it
+// decodes as. This should only be used when one of several specific messages are expected, and those messages did not
+// call AddMessageHeader in their init() functions. Messages given first have a higher matching priority. The returned
+// bool is false when none of the messages matched. Only returns an error on connection errors, or when the message
+// length is invalid.
+func ReceiveIntoAny(conn net.Conn, messages ...Message) (Message, bool, error) {
+	buffer, release, err := readMessage(conn, false)
+	if err != nil {
+		return nil, false, err
 	}
-	db := newDecodeBuffer(buffer)
-
-	// This dual loop is used to process the given messages from the buffer.
-	// For each step of the buffer, we try decoding each message given. If that message properly decodes, then we
-	// finalize the changes on the buffer, and check the next message. As the first message has priority, we always
-	// start the loop over the first message. If any message does not properly parse, then the buffer is reset to the
-	// beginning of that specific loop. With this setup, the only way we will reach the break is once none of the
-	// messages parse.
-	var outMessages []Message
-OuterLoop:
-	for {
-		for _, message := range messages {
-			outMessage, err := receiveFromBuffer(db, message)
-			if err == nil {
-				outMessages = append(outMessages, outMessage)
-				db.next()
-				continue OuterLoop
-			} else {
-				db.reset()
-			}
+	defer release()
+
+	for _, message := range messages {
+		if outMessage, err := receiveFromBuffer(newDecodeBuffer(buffer), message); err == nil {
+			recordMessage(conn, Direction_Frontend, outMessage, buffer)
+			return outMessage, true, nil
 		}
-		// If we've reached this point, then we've attempted to match all of the given messages.
-		break
 	}
-
-	return outMessages, nil
+	return nil, false, nil
 }
 
 // ReceiveBruteForceMatches checks with every message to find matches. This is highly inefficient, and is intended to
@@ -187,8 +182,54 @@ func Send(conn net.Conn, message Message) error {
 	if err != nil {
 		return err
 	}
-	_, err = conn.Write(data)
-	return err
+	if _, err = conn.Write(data); err != nil {
+		return err
+	}
+	recordMessage(conn, Direction_Backend, message, data)
+	return nil
+}
+
+// readMessage reads a single, complete message from the connection. Messages that have a header start with a single
+// byte, while all messages contain their length (which includes the length itself) as an Int32. Reads are repeated
+// until the entire message has arrived, as a message may be split across any number of network packets. The returned
+// function must be called once the buffer is no longer needed, so that pooled buffers may be reused.
+func readMessage(conn net.Conn, hasHeader bool) ([]byte, func(), error) {
+	prefixSize := lengthSize
+	maxSize := int32(maxStartupMessageSize)
+	if hasHeader {
+		prefixSize = headerSize
+		maxSize = maxMessageSize
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(conn, prefix); err != nil {
+		return nil, nil, err
+	}
+	messageLength := int32(binary.BigEndian.Uint32(prefix[prefixSize-lengthSize:]))
+	if messageLength < lengthSize {
+		return nil, nil, fmt.Errorf("invalid message length: %d", messageLength)
+	}
+	if messageLength > maxSize {
+		return nil, nil, fmt.Errorf("message length of %d exceeds the maximum of %d", messageLength, maxSize)
+	}
+
+	totalSize := int(messageLength) + prefixSize - lengthSize
+	var buffer []byte
+	release := func() {}
+	if totalSize <= bufferSize {
+		pooledBuffer := connBuffers.Get().([]byte)
+		release = func() {
+			connBuffers.Put(zeroBuffer(pooledBuffer))
+		}
+		buffer = pooledBuffer[:totalSize]
+	} else {
+		buffer = make([]byte, totalSize)
+	}
+	copy(buffer, prefix)
+	if _, err := io.ReadFull(conn, buffer[prefixSize:]); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return buffer, release, nil
 }
 
 // receiveFromBuffer writes the contents of the buffer into the given Message.
@@ -208,6 +249,12 @@ func receiveFromBuffer[T Message](buffer *decodeBuffer, message T) (out T, err e
 	return decodedMessage.(T), nil
 }
 
+// hasHeader returns whether the given message starts with a header. A header is always the first field of a message.
+func hasHeader(message Message) bool {
+	fields := message.DefaultMessage().Fields
+	return len(fields) > 0 && fields[0].Flags&Header != 0
+}
+
 // zeroBuffer fills the given buffer with zeroes, returning the same buffer that was given.
 func zeroBuffer(buffer []byte) []byte {
 	copy(buffer, sliceOfZeroes)
@@ -0,0 +1,172 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Direction is the side of the connection that a recorded message was sent from.
+type Direction string
+
+const (
+	Direction_Frontend Direction = "frontend"
+	Direction_Backend  Direction = "backend"
+)
+
+// redactedMessages are the messages that may contain a password or proof of one. Only their names are recorded.
+var redactedMessages = map[string]struct{}{
+	"PasswordMessage":     {},
+	"SASLInitialResponse": {},
+	"SASLResponse":        {},
+	"GSSResponse":         {},
+}
+
+// RecordedMessage is a single message that was written to a session recording.
+type RecordedMessage struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"direction"`
+	// Name is the name of the message, such as "Query" or "DataRow".
+	Name string `json:"name"`
+	// Message is a printable form of the decoded message.
+	Message string `json:"message,omitempty"`
+	// Data is the message exactly as it was sent over the connection.
+	Data []byte `json:"data,omitempty"`
+}
+
+// String returns a printable version of the RecordedMessage.
+func (m RecordedMessage) String() string {
+	return fmt.Sprintf("%s %s %s", m.Direction, m.Name, m.Message)
+}
+
+// Recorder writes every message that is sent or received over its connections to a session recording, which has a
+// single JSON-encoded RecordedMessage on each line. Messages are recorded from the server's perspective, so received
+// messages were sent by the frontend, while sent messages were sent by the backend. Authentication messages are
+// redacted, as they may contain passwords.
+type Recorder struct {
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+	err    error
+}
+
+// RecordingConn is a connection whose messages are recorded by a Recorder. Only the messages that are sent and received
+// through this package are recorded.
+type RecordingConn struct {
+	net.Conn
+	recorder *Recorder
+}
+
+var _ net.Conn = (*RecordingConn)(nil)
+
+// NewRecorder creates a Recorder that writes to the file at the given path, which must not already exist.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file}, nil
+}
+
+// Wrap returns a connection that records the messages of the given connection. A connection that wraps another, such
+// as an SSL connection, must wrap the original connection rather than the returned one.
+func (r *Recorder) Wrap(conn net.Conn) *RecordingConn {
+	return &RecordingConn{Conn: conn, recorder: r}
+}
+
+// Close stops recording the wrapped connections and closes the file. Returns the first error that occurred while
+// writing the recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Unwrap returns the connection that is being recorded.
+func (c *RecordingConn) Unwrap() net.Conn {
+	return c.Conn
+}
+
+// Unwrap returns the given connection without its recording, if it's being recorded.
+func Unwrap(conn net.Conn) net.Conn {
+	if recordingConn, ok := conn.(*RecordingConn); ok {
+		return recordingConn.Unwrap()
+	}
+	return conn
+}
+
+// record writes the given message, along with its data, to the recording. Errors are kept until the Recorder is
+// closed, as recording must not interfere with the connection.
+func (r *Recorder) record(direction Direction, message Message, data []byte) {
+	recorded := RecordedMessage{
+		Time:      time.Now(),
+		Direction: direction,
+		Name:      message.DefaultMessage().Name,
+	}
+	if _, ok := redactedMessages[recorded.Name]; !ok {
+		recorded.Message = fmt.Sprintf("%+v", message)
+		recorded.Data = data
+	}
+	encoded, err := json.Marshal(recorded)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil || r.closed {
+		return
+	}
+	if _, err = r.file.Write(append(encoded, '\n')); err != nil {
+		r.err = err
+	}
+}
+
+// ReadRecording reads the session recording at the given path.
+func ReadRecording(path string) ([]RecordedMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var recording []RecordedMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxMessageSize*2)
+	for scanner.Scan() {
+		var message RecordedMessage
+		if err = json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("invalid message on line %d of %s: %w", len(recording)+1, path, err)
+		}
+		recording = append(recording, message)
+	}
+	return recording, scanner.Err()
+}
+
+// recordMessage records the given message if the connection is being recorded.
+func recordMessage(conn net.Conn, direction Direction, message Message, data []byte) {
+	if recordingConn, ok := conn.(*RecordingConn); ok {
+		recordingConn.recorder.record(direction, message, data)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connection
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// replayTimeout is how long Replay waits for each of the server's responses.
+const replayTimeout = 30 * time.Second
+
+// unreplayedMessages are the messages that Replay skips. Replays do not negotiate SSL, and they connect to servers that
+// do not require authentication, so the messages that belong to either exchange would not be sent or received.
+var unreplayedMessages = map[string]struct{}{
+	"SSLRequest":                      {},
+	"SSLResponse":                     {},
+	"GSSENCRequest":                   {},
+	"GSSENCResponse":                  {},
+	"PasswordMessage":                 {},
+	"SASLInitialResponse":             {},
+	"SASLResponse":                    {},
+	"GSSResponse":                     {},
+	"AuthenticationCleartextPassword": {},
+	"AuthenticationGSS":               {},
+	"AuthenticationGSSContinue":       {},
+	"AuthenticationKerberosV5":        {},
+	"AuthenticationMD5Password":       {},
+	"AuthenticationSASL":              {},
+	"AuthenticationSASLContinue":      {},
+	"AuthenticationSASLFinal":         {},
+	"AuthenticationSCMCredential":     {},
+	"AuthenticationSSPI":              {},
+}
+
+// unstableFields are the fields of each message that differ between sessions, which are left out when the message is
+// compared. Each field is given as the range of its bytes within the message, which begins with the header and length.
+var unstableFields = map[string][][2]int{
+	"BackendKeyData":       {{5, 13}}, // ProcessID and SecretKey
+	"NotificationResponse": {{5, 9}},  // ProcessID of the notifying backend
+}
+
+// ReplayDifference is a response from the server that differs from the recorded session.
+type ReplayDifference struct {
+	// Expected is the recorded message, which is nil when the server sent a message that was not recorded.
+	Expected *RecordedMessage
+	// Actual is a printable form of the message that the server sent, which is empty when the server did not send the
+	// recorded message.
+	Actual string
+}
+
+// String returns a printable version of the ReplayDifference.
+func (d ReplayDifference) String() string {
+	switch {
+	case d.Expected == nil:
+		return fmt.Sprintf("received an unexpected message: %s", d.Actual)
+	case len(d.Actual) == 0:
+		return fmt.Sprintf("did not receive: %s", describeMessage(d.Expected.Data, d.Expected.Name))
+	default:
+		return fmt.Sprintf("expected: %s\nreceived: %s", describeMessage(d.Expected.Data, d.Expected.Name), d.Actual)
+	}
+}
+
+// Replay sends the frontend messages of the given recording over the connection, and compares the server's responses
+// with the recorded backend messages. The session is replayed a cycle at a time, where each cycle ends with the
+// server's ReadyForQuery: every frontend message of the cycle is sent, and then every response until the next
+// ReadyForQuery is compared, as the server may send a different number of messages than were recorded. Returns an
+// error when the connection fails, including when the server does not send a response in time.
+func Replay(conn net.Conn, recording []RecordedMessage) ([]ReplayDifference, error) {
+	var differences []ReplayDifference
+	var responses []RecordedMessage
+	for _, message := range recording {
+		if _, ok := unreplayedMessages[message.Name]; ok {
+			continue
+		}
+		if message.Direction == Direction_Frontend {
+			if _, err := conn.Write(message.Data); err != nil {
+				return differences, err
+			}
+			continue
+		}
+		responses = append(responses, message)
+		if isReadyForQuery(message.Data) {
+			cycleDifferences, err := compareResponses(conn, responses)
+			differences = append(differences, cycleDifferences...)
+			if err != nil {
+				return differences, err
+			}
+			responses = nil
+		}
+	}
+	cycleDifferences, err := compareResponses(conn, responses)
+	return append(differences, cycleDifferences...), err
+}
+
+// ReplaySessions replays each of the given recordings, in order, over its own connection to the server at the given
+// address. Returns every difference between the server's responses and the recorded responses.
+func ReplaySessions(address string, recordings ...[]RecordedMessage) ([]ReplayDifference, error) {
+	var differences []ReplayDifference
+	for _, recording := range recordings {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return differences, err
+		}
+		sessionDifferences, err := Replay(conn, recording)
+		differences = append(differences, sessionDifferences...)
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return differences, err
+		}
+	}
+	return differences, nil
+}
+
+// readyForQueryHeader is the header of ReadyForQuery, which ends the server's response to each query.
+const readyForQueryHeader = 'Z'
+
+// isReadyForQuery returns whether the given message data is a ReadyForQuery. Recordings may be edited by hand, so the
+// data may be empty.
+func isReadyForQuery(data []byte) bool {
+	return len(data) > 0 && data[0] == readyForQueryHeader
+}
+
+// compareResponses reads the server's responses, and compares them with the given recorded responses. When the
+// recorded responses end with a ReadyForQuery, every response until the next ReadyForQuery is read. Otherwise, these
+// are the final responses of the session, so responses are read until the same number of messages as were recorded
+// have been read, or until the server closes the connection.
+func compareResponses(conn net.Conn, expected []RecordedMessage) ([]ReplayDifference, error) {
+	if len(expected) == 0 {
+		return nil, nil
+	}
+	endsWithReadyForQuery := isReadyForQuery(expected[len(expected)-1].Data)
+	var actual [][]byte
+	for {
+		if endsWithReadyForQuery && len(actual) > 0 && isReadyForQuery(actual[len(actual)-1]) {
+			break
+		}
+		if !endsWithReadyForQuery && len(actual) == len(expected) {
+			break
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(replayTimeout)); err != nil {
+			return nil, err
+		}
+		buffer, release, err := readMessage(conn, true)
+		if err == io.EOF && !endsWithReadyForQuery {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		actual = append(actual, append([]byte(nil), buffer...))
+		release()
+	}
+	return diffResponses(expected, actual), nil
+}
+
+// diffResponses returns the differences between the recorded and actual responses. The responses are aligned by their
+// longest common subsequence, so that a missing or unexpected message is reported on its own. Within each unaligned
+// run, recorded and actual messages are paired up in order.
+func diffResponses(expected []RecordedMessage, actual [][]byte) []ReplayDifference {
+	// lengths[i][j] is the length of the longest common subsequence of expected[i:] and actual[j:]
+	lengths := make([][]int, len(expected)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(actual)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(actual) - 1; j >= 0; j-- {
+			if matchesRecording(expected[i], actual[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var differences []ReplayDifference
+	var missing []*RecordedMessage
+	var unexpected []string
+	flush := func() {
+		for len(missing) > 0 || len(unexpected) > 0 {
+			var difference ReplayDifference
+			if len(missing) > 0 {
+				difference.Expected = missing[0]
+				missing = missing[1:]
+			}
+			if len(unexpected) > 0 {
+				difference.Actual = unexpected[0]
+				unexpected = unexpected[1:]
+			}
+			differences = append(differences, difference)
+		}
+	}
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case i < len(expected) && j < len(actual) && matchesRecording(expected[i], actual[j]):
+			flush()
+			i++
+			j++
+		case j == len(actual) || (i < len(expected) && lengths[i+1][j] >= lengths[i][j+1]):
+			missing = append(missing, &expected[i])
+			i++
+		default:
+			name := ""
+			if i < len(expected) {
+				name = expected[i].Name
+			}
+			unexpected = append(unexpected, describeMessage(actual[j], name))
+			j++
+		}
+	}
+	flush()
+	return differences
+}
+
+// matchesRecording returns whether the given message data matches the recorded message.
+func matchesRecording(expected RecordedMessage, actual []byte) bool {
+	fields, ok := unstableFields[expected.Name]
+	if !ok {
+		return bytes.Equal(expected.Data, actual)
+	}
+	if len(expected.Data) != len(actual) {
+		return false
+	}
+	masked := append([]byte(nil), actual...)
+	for _, field := range fields {
+		// Both messages have the same length, so a field that fits within one fits within the other
+		if field[1] <= len(masked) {
+			copy(masked[field[0]:field[1]], expected.Data[field[0]:field[1]])
+		}
+	}
+	return bytes.Equal(expected.Data, masked)
+}
+
+// describeMessage returns a printable form of the given message data, which lists the value of each field. Messages
+// that are only sent by the backend do not support decoding, so the data is read into the fields of the message
+// structure that it matches. The message with the given name is tried first, followed by the messages that share its
+// header, as a backend message may share its header with a frontend message.
+func describeMessage(data []byte, name string) string {
+	if len(data) == 0 {
+		return fmt.Sprintf("%s with no data", name)
+	}
+	var candidates []Message
+	var frontendCandidates []Message
+	for _, message := range allMessages {
+		fields := message.DefaultMessage().Fields
+		if !hasHeader(message) || byte(fields[0].Data.(int32)) != data[0] {
+			continue
+		}
+		if message.DefaultMessage().Name == name {
+			candidates = append([]Message{message}, candidates...)
+		} else if received, ok := allMessageHeaders[data[0]]; ok && received.DefaultMessage().Name == message.DefaultMessage().Name {
+			frontendCandidates = append(frontendCandidates, message)
+		} else {
+			candidates = append(candidates, message)
+		}
+	}
+	for _, candidate := range append(candidates, frontendCandidates...) {
+		fields := candidate.DefaultMessage().Copy().Fields
+		buffer := newDecodeBuffer(data)
+		if err := decode(buffer, []FieldGroup{fields}, 1); err == nil && len(buffer.data) == 0 {
+			return fmt.Sprintf("%s %s", candidate.DefaultMessage().Name, formatFields(fields))
+		}
+	}
+	return fmt.Sprintf("unknown message %q", data)
+}
+
+// formatFields returns a printable form of the given fields, excluding the header and message length.
+func formatFields(fields FieldGroup) string {
+	var values []string
+	for _, field := range fields {
+		if field.Flags&(Header|MessageLengthInclusive|MessageLengthExclusive) != 0 {
+			continue
+		}
+		switch data := field.Data.(type) {
+		case string:
+			values = append(values, fmt.Sprintf("%s:%q", field.Name, data))
+		case []byte:
+			values = append(values, fmt.Sprintf("%s:%q", field.Name, data))
+		default:
+			if len(field.Children) == 0 {
+				values = append(values, fmt.Sprintf("%s:%v", field.Name, data))
+				continue
+			}
+			children := make([]string, len(field.Children))
+			for i, child := range field.Children {
+				children[i] = formatFields(child)
+			}
+			values = append(values, fmt.Sprintf("%s:[%s]", field.Name, strings.Join(children, " ")))
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(values, " "))
+}
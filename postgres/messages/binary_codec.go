@@ -0,0 +1,122 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
+	"github.com/lib/pq/oid"
+
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+)
+
+const (
+	// FormatCode_Text is the format code for values in the text format.
+	FormatCode_Text int32 = 0
+	// FormatCode_Binary is the format code for values in the binary format.
+	FormatCode_Binary int32 = 1
+)
+
+// BinaryEncoder encodes a value into the binary format of a Postgres type, as defined by the type's send function.
+type BinaryEncoder func(value sqltypes.Value) ([]byte, error)
+
+// BinaryDecoder decodes a value from the binary format of a Postgres type, as defined by the type's receive function.
+type BinaryDecoder func(data []byte) (tree.Datum, error)
+
+// BinaryCodec converts values to and from the binary format of a Postgres type. Types that results are never reported
+// as, but which clients may still send, only have a decoder.
+type BinaryCodec struct {
+	Encode BinaryEncoder
+	Decode BinaryDecoder
+}
+
+// binaryCodecs contains the binary codec for each supported type, keyed by the type's object ID.
+var binaryCodecs = map[oid.Oid]BinaryCodec{
+	oid.T_bool:        {Encode: encodeBinaryBool, Decode: decodeBinaryBool},
+	oid.T_bytea:       {Encode: encodeBinaryBytes, Decode: decodeBinaryBytes},
+	oid.T_int2:        {Encode: encodeBinaryInt(2), Decode: decodeBinaryInt(2)},
+	oid.T_int4:        {Encode: encodeBinaryInt(4), Decode: decodeBinaryInt(4)},
+	oid.T_int8:        {Encode: encodeBinaryInt(8), Decode: decodeBinaryInt(8)},
+	oid.T_float4:      {Encode: encodeBinaryFloat(4), Decode: decodeBinaryFloat(4)},
+	oid.T_float8:      {Encode: encodeBinaryFloat(8), Decode: decodeBinaryFloat(8)},
+	oid.T_numeric:     {Encode: encodeBinaryNumeric, Decode: decodeBinaryNumeric},
+	oid.T_date:        {Encode: encodeBinaryDate, Decode: decodeBinaryDate},
+	oid.T_timestamp:   {Encode: encodeBinaryTimestamp, Decode: decodeBinaryTimestamp},
+	oid.T_timestamptz: {Decode: decodeBinaryTimestampTZ},
+	oid.T_uuid:        {Decode: decodeBinaryUuid},
+	oid.T_text:        {Encode: encodeBinaryBytes, Decode: decodeBinaryString},
+	oid.T_varchar:     {Encode: encodeBinaryBytes, Decode: decodeBinaryString},
+	oid.T_bpchar:      {Encode: encodeBinaryBytes, Decode: decodeBinaryString},
+	oid.T_name:        {Decode: decodeBinaryString},
+	oid.T_json:        {Encode: encodeBinaryBytes, Decode: decodeBinaryString},
+	oid.T__int2:       {Decode: decodeBinaryArray(oid.T_int2, decodeBinaryInt(2))},
+	oid.T__int4:       {Decode: decodeBinaryArray(oid.T_int4, decodeBinaryInt(4))},
+	oid.T__int8:       {Decode: decodeBinaryArray(oid.T_int8, decodeBinaryInt(8))},
+	oid.T__text:       {Decode: decodeBinaryArray(oid.T_text, decodeBinaryString)},
+	oid.T__varchar:    {Decode: decodeBinaryArray(oid.T_varchar, decodeBinaryString)},
+	oid.T__bpchar:     {Decode: decodeBinaryArray(oid.T_bpchar, decodeBinaryString)},
+}
+
+// postgresEpoch is the epoch that Postgres uses for its binary date and time formats.
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// EncodeValue encodes the given value using the given format code. The value's Postgres type is determined from the
+// field that describes it, or from its Vitess type when the field is nil.
+func EncodeValue(field *query.Field, value sqltypes.Value, formatCode int32) ([]byte, error) {
+	if field == nil {
+		field = &query.Field{Type: value.Type()}
+	}
+	switch formatCode {
+	case FormatCode_Text:
+		if isBooleanField(field) {
+			return encodeTextBool(value)
+		}
+		return []byte(value.ToString()), nil
+	case FormatCode_Binary:
+		objectID, err := VitessFieldToDataTypeObjectID(field)
+		if err != nil {
+			return nil, err
+		}
+		return EncodeBinary(oid.Oid(objectID), value)
+	default:
+		return nil, fmt.Errorf("unknown format code %d", formatCode)
+	}
+}
+
+// EncodeBinary encodes the given value into the binary format of the type with the given object ID.
+func EncodeBinary(objectID oid.Oid, value sqltypes.Value) ([]byte, error) {
+	codec, ok := binaryCodecs[objectID]
+	if !ok || codec.Encode == nil {
+		return nil, binaryFormatNotSupportedError(objectID)
+	}
+	return codec.Encode(value)
+}
+
+// DecodeBinary decodes the given data from the binary format of the type with the given object ID.
+func DecodeBinary(objectID oid.Oid, data []byte) (tree.Datum, error) {
+	codec, ok := binaryCodecs[objectID]
+	if !ok || codec.Decode == nil {
+		return nil, binaryFormatNotSupportedError(objectID)
+	}
+	return codec.Decode(data)
+}
+
+// binaryFormatNotSupportedError returns the error for a type whose binary format is not supported.
+func binaryFormatNotSupportedError(objectID oid.Oid) error {
+	return fmt.Errorf("the binary format is not yet supported for the type with OID %d", objectID)
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lib/pq/oid"
+
+	"github.com/dolthub/doltgresql/postgres/parser/pgdate"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+	"github.com/dolthub/doltgresql/postgres/parser/types"
+	"github.com/dolthub/doltgresql/postgres/parser/uuid"
+)
+
+// checkBinaryLength returns an error if the data does not have the expected length.
+func checkBinaryLength(data []byte, length int) error {
+	if len(data) != length {
+		return fmt.Errorf("insufficient data left in message: expected %d bytes but found %d", length, len(data))
+	}
+	return nil
+}
+
+// decodeBinaryBool decodes a boolean from a single byte.
+func decodeBinaryBool(data []byte) (tree.Datum, error) {
+	if err := checkBinaryLength(data, 1); err != nil {
+		return nil, err
+	}
+	return tree.MakeDBool(data[0] != 0), nil
+}
+
+// decodeBinaryBytes decodes bytes, which are sent as-is.
+func decodeBinaryBytes(data []byte) (tree.Datum, error) {
+	return tree.NewDBytes(tree.DBytes(data)), nil
+}
+
+// decodeBinaryString decodes a string, which is sent as-is.
+func decodeBinaryString(data []byte) (tree.Datum, error) {
+	return tree.NewDString(string(data)), nil
+}
+
+// decodeBinaryInt returns a decoder for a big-endian integer with the given size in bytes.
+func decodeBinaryInt(size int) BinaryDecoder {
+	return func(data []byte) (tree.Datum, error) {
+		if err := checkBinaryLength(data, size); err != nil {
+			return nil, err
+		}
+		switch size {
+		case 2:
+			return tree.NewDInt(tree.DInt(int16(binary.BigEndian.Uint16(data)))), nil
+		case 4:
+			return tree.NewDInt(tree.DInt(int32(binary.BigEndian.Uint32(data)))), nil
+		default:
+			return tree.NewDInt(tree.DInt(int64(binary.BigEndian.Uint64(data)))), nil
+		}
+	}
+}
+
+// decodeBinaryFloat returns a decoder for a big-endian IEEE 754 float with the given size in bytes.
+func decodeBinaryFloat(size int) BinaryDecoder {
+	return func(data []byte) (tree.Datum, error) {
+		if err := checkBinaryLength(data, size); err != nil {
+			return nil, err
+		}
+		if size == 4 {
+			return tree.NewDFloat(tree.DFloat(math.Float32frombits(binary.BigEndian.Uint32(data)))), nil
+		}
+		return tree.NewDFloat(tree.DFloat(math.Float64frombits(binary.BigEndian.Uint64(data)))), nil
+	}
+}
+
+// decodeBinaryNumeric decodes a decimal from a header followed by its digits in base 10000. The header contains the
+// number of digits, the weight of the first digit, the sign, and the display scale.
+func decodeBinaryNumeric(data []byte) (tree.Datum, error) {
+	if len(data) < 8 {
+		return nil, checkBinaryLength(data, 8)
+	}
+	digitCount := int(binary.BigEndian.Uint16(data[0:]))
+	weight := int(int16(binary.BigEndian.Uint16(data[2:])))
+	sign := binary.BigEndian.Uint16(data[4:])
+	displayScale := int(binary.BigEndian.Uint16(data[6:]))
+	if err := checkBinaryLength(data, 8+2*digitCount); err != nil {
+		return nil, err
+	}
+	switch sign {
+	case 0x0000, 0x4000:
+	case 0xC000:
+		return tree.ParseDDecimal("NaN")
+	default:
+		return nil, fmt.Errorf("invalid sign in external \"numeric\" value")
+	}
+	digitAt := func(i int) uint16 {
+		if i < 0 || i >= digitCount {
+			return 0
+		}
+		return binary.BigEndian.Uint16(data[8+2*i:])
+	}
+	// Each base 10000 digit is written as four decimal digits, with the decimal point placed after the weight's digit
+	integer := strings.Builder{}
+	for i := 0; i <= weight; i++ {
+		integer.WriteString(fmt.Sprintf("%04d", digitAt(i)))
+	}
+	fraction := strings.Builder{}
+	for i := 0; i < (displayScale+3)/4; i++ {
+		fraction.WriteString(fmt.Sprintf("%04d", digitAt(weight+1+i)))
+	}
+	str := strings.TrimLeft(integer.String(), "0")
+	if len(str) == 0 {
+		str = "0"
+	}
+	if displayScale > 0 {
+		str += "." + fraction.String()[:displayScale]
+	}
+	if sign == 0x4000 {
+		str = "-" + str
+	}
+	return tree.ParseDDecimal(str)
+}
+
+// decodeBinaryDate decodes a date from the number of days since the Postgres epoch.
+func decodeBinaryDate(data []byte) (tree.Datum, error) {
+	if err := checkBinaryLength(data, 4); err != nil {
+		return nil, err
+	}
+	date, err := pgdate.MakeDateFromPGEpoch(int32(binary.BigEndian.Uint32(data)))
+	if err != nil {
+		return nil, err
+	}
+	return tree.NewDDate(date), nil
+}
+
+// decodeBinaryTimestamp decodes a timestamp from the number of microseconds since the Postgres epoch.
+func decodeBinaryTimestamp(data []byte) (tree.Datum, error) {
+	t, err := decodeBinaryTime(data)
+	if err != nil {
+		return nil, err
+	}
+	return tree.MakeDTimestamp(t, time.Microsecond)
+}
+
+// decodeBinaryTimestampTZ decodes a timestamp with a time zone from the number of microseconds since the Postgres
+// epoch in UTC.
+func decodeBinaryTimestampTZ(data []byte) (tree.Datum, error) {
+	t, err := decodeBinaryTime(data)
+	if err != nil {
+		return nil, err
+	}
+	return tree.MakeDTimestampTZ(t, time.Microsecond)
+}
+
+// decodeBinaryTime decodes the number of microseconds since the Postgres epoch.
+func decodeBinaryTime(data []byte) (time.Time, error) {
+	if err := checkBinaryLength(data, 8); err != nil {
+		return time.Time{}, err
+	}
+	microseconds := int64(binary.BigEndian.Uint64(data))
+	if microseconds == math.MaxInt64 || microseconds == math.MinInt64 {
+		return time.Time{}, fmt.Errorf("infinite timestamps are not yet supported")
+	}
+	return time.Unix(postgresEpoch.Unix()+microseconds/1000000, (microseconds%1000000)*1000).UTC(), nil
+}
+
+// decodeBinaryUuid decodes a UUID from its 16 bytes.
+func decodeBinaryUuid(data []byte) (tree.Datum, error) {
+	id, err := uuid.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return tree.NewDUuid(tree.DUuid{UUID: id}), nil
+}
+
+// decodeBinaryArray returns a decoder for a one-dimensional array of the given element type, which uses the given
+// decoder for each element. The array's header contains the number of dimensions, a flag that is set when the array
+// contains NULLs, and the element type. Each dimension then has its length and lower bound, which are followed by each
+// element's length and data.
+func decodeBinaryArray(elementObjectID oid.Oid, elementDecoder BinaryDecoder) BinaryDecoder {
+	return func(data []byte) (tree.Datum, error) {
+		array := tree.NewDArray(types.OidToType[elementObjectID])
+		if len(data) < 12 {
+			return nil, checkBinaryLength(data, 12)
+		}
+		dimensions := int32(binary.BigEndian.Uint32(data[0:]))
+		if dataObjectID := oid.Oid(binary.BigEndian.Uint32(data[8:])); dataObjectID != elementObjectID {
+			return nil, fmt.Errorf("wrong element type: expected OID %d but found %d", elementObjectID, dataObjectID)
+		}
+		data = data[12:]
+		switch dimensions {
+		case 0:
+			return array, nil
+		case 1:
+		default:
+			return nil, fmt.Errorf("multidimensional arrays are not yet supported")
+		}
+		if len(data) < 8 {
+			return nil, checkBinaryLength(data, 8)
+		}
+		elementCount := int(int32(binary.BigEndian.Uint32(data)))
+		data = data[8:]
+		for i := 0; i < elementCount; i++ {
+			if len(data) < 4 {
+				return nil, checkBinaryLength(data, 4)
+			}
+			length := int(int32(binary.BigEndian.Uint32(data)))
+			data = data[4:]
+			if length == -1 {
+				if err := array.Append(tree.DNull); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if length < 0 || len(data) < length {
+				return nil, fmt.Errorf("insufficient data left in message")
+			}
+			element, err := elementDecoder(data[:length])
+			if err != nil {
+				return nil, err
+			}
+			if err = array.Append(element); err != nil {
+				return nil, err
+			}
+			data = data[length:]
+		}
+		if len(data) != 0 {
+			return nil, fmt.Errorf("incorrect binary data format")
+		}
+		return array, nil
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dolthub/vitess/go/sqltypes"
+)
+
+// boolValue returns the boolean that the given value holds. Booleans are stored as integers, where every value other
+// than zero is true.
+func boolValue(value sqltypes.Value) (bool, error) {
+	val, err := strconv.ParseInt(value.ToString(), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean: %s", value.ToString())
+	}
+	return val != 0, nil
+}
+
+// encodeBinaryBool encodes a boolean as a single byte.
+func encodeBinaryBool(value sqltypes.Value) ([]byte, error) {
+	val, err := boolValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if val {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+// encodeTextBool encodes a boolean as t or f, which is the text format that Postgres uses for booleans.
+func encodeTextBool(value sqltypes.Value) ([]byte, error) {
+	val, err := boolValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if val {
+		return []byte("t"), nil
+	}
+	return []byte("f"), nil
+}
+
+// encodeBinaryBytes encodes the value as its raw bytes, which is the binary format for all string-like types.
+func encodeBinaryBytes(value sqltypes.Value) ([]byte, error) {
+	return value.ToBytes(), nil
+}
+
+// encodeBinaryInt returns an encoder for a big-endian integer with the given size in bytes.
+func encodeBinaryInt(size int) BinaryEncoder {
+	return func(value sqltypes.Value) ([]byte, error) {
+		val, err := strconv.ParseInt(value.ToString(), 10, size*8)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(val))
+		return data[8-size:], nil
+	}
+}
+
+// encodeBinaryFloat returns an encoder for a big-endian IEEE 754 float with the given size in bytes.
+func encodeBinaryFloat(size int) BinaryEncoder {
+	return func(value sqltypes.Value) ([]byte, error) {
+		val, err := strconv.ParseFloat(value.ToString(), size*8)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if size == 4 {
+			binary.BigEndian.PutUint32(data, math.Float32bits(float32(val)))
+		} else {
+			binary.BigEndian.PutUint64(data, math.Float64bits(val))
+		}
+		return data, nil
+	}
+}
+
+// encodeBinaryDate encodes a date as the number of days since the Postgres epoch.
+func encodeBinaryDate(value sqltypes.Value) ([]byte, error) {
+	t, err := time.Parse("2006-01-02", value.ToString())
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(int32((t.Unix()-postgresEpoch.Unix())/86400)))
+	return data, nil
+}
+
+// encodeBinaryTimestamp encodes a timestamp as the number of microseconds since the Postgres epoch.
+func encodeBinaryTimestamp(value sqltypes.Value) ([]byte, error) {
+	t, err := time.Parse("2006-01-02 15:04:05.999999", value.ToString())
+	if err != nil {
+		return nil, err
+	}
+	microseconds := (t.Unix()-postgresEpoch.Unix())*1000000 + int64(t.Nanosecond()/1000)
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(microseconds))
+	return data, nil
+}
+
+// encodeBinaryNumeric encodes a decimal as a header followed by its digits in base 10000. The header contains the
+// number of digits, the weight of the first digit, the sign, and the display scale.
+func encodeBinaryNumeric(value sqltypes.Value) ([]byte, error) {
+	str := value.ToString()
+	sign := uint16(0)
+	if strings.HasPrefix(str, "-") {
+		sign = 0x4000
+		str = str[1:]
+	}
+	integer, fraction, _ := strings.Cut(str, ".")
+	if strings.Trim(integer+fraction, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid numeric: %s", value.ToString())
+	}
+	displayScale := len(fraction)
+	// Pad both sides so that the digits may be split into groups of four decimal digits
+	if len(integer)%4 != 0 {
+		integer = strings.Repeat("0", 4-len(integer)%4) + integer
+	}
+	if len(fraction)%4 != 0 {
+		fraction = fraction + strings.Repeat("0", 4-len(fraction)%4)
+	}
+	allDigits := integer + fraction
+	digits := make([]uint16, 0, len(allDigits)/4)
+	for i := 0; i < len(allDigits); i += 4 {
+		digit, _ := strconv.ParseUint(allDigits[i:i+4], 10, 16)
+		digits = append(digits, uint16(digit))
+	}
+	weight := len(integer)/4 - 1
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+		weight--
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == 0 {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 {
+		weight = 0
+		sign = 0
+	}
+	data := make([]byte, 8+2*len(digits))
+	binary.BigEndian.PutUint16(data[0:], uint16(len(digits)))
+	binary.BigEndian.PutUint16(data[2:], uint16(int16(weight)))
+	binary.BigEndian.PutUint16(data[4:], sign)
+	binary.BigEndian.PutUint16(data[6:], uint16(displayScale))
+	for i, digit := range digits {
+		binary.BigEndian.PutUint16(data[8+2*i:], digit)
+	}
+	return data, nil
+}
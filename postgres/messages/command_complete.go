@@ -81,6 +81,20 @@ func (m CommandComplete) Encode() (connection.MessageFormat, error) {
 		outputMessage.Field("CommandTag").MustWrite(fmt.Sprintf("UPDATE %d", m.Rows))
 	} else if strings.HasPrefix(query, "delete") {
 		outputMessage.Field("CommandTag").MustWrite(fmt.Sprintf("DELETE %d", m.Rows))
+	} else if strings.HasPrefix(query, "copy") {
+		outputMessage.Field("CommandTag").MustWrite(fmt.Sprintf("COPY %d", m.Rows))
+	} else if strings.HasPrefix(query, "set") {
+		outputMessage.Field("CommandTag").MustWrite("SET")
+	} else if strings.HasPrefix(query, "reset") {
+		outputMessage.Field("CommandTag").MustWrite("RESET")
+	} else if strings.HasPrefix(query, "show") {
+		outputMessage.Field("CommandTag").MustWrite("SHOW")
+	} else if strings.HasPrefix(query, "listen") {
+		outputMessage.Field("CommandTag").MustWrite("LISTEN")
+	} else if strings.HasPrefix(query, "unlisten") {
+		outputMessage.Field("CommandTag").MustWrite("UNLISTEN")
+	} else if strings.HasPrefix(query, "notify") {
+		outputMessage.Field("CommandTag").MustWrite("NOTIFY")
 	} else {
 		// We'll just default to SELECT since that seems to be the return value for a lot of query types.
 		outputMessage.Field("CommandTag").MustWrite(fmt.Sprintf("SELECT %d", m.Rows))
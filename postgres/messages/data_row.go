@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
 
 	"github.com/dolthub/doltgresql/postgres/connection"
 )
@@ -26,9 +27,13 @@ func init() {
 	connection.InitializeDefaultMessage(DataRow{})
 }
 
-// DataRow represents a row of data.
+// DataRow represents a row of data. Fields contains the field that describes each value, which are the same fields
+// as the RowDescription for the row, and values without a field are encoded according to their own type. FormatCodes
+// contains the format code of each value, and values without a format code use the text format.
 type DataRow struct {
-	Values []sqltypes.Value
+	Values      []sqltypes.Value
+	Fields      []*query.Field
+	FormatCodes []int32
 }
 
 var dataRowDefault = connection.MessageFormat{
@@ -78,7 +83,18 @@ func (m DataRow) Encode() (connection.MessageFormat, error) {
 		if m.Values[i].IsNull() {
 			outputMessage.Field("Columns").Child("ColumnLength", i).MustWrite(-1)
 		} else {
-			value := []byte(m.Values[i].ToString())
+			formatCode := FormatCode_Text
+			if i < len(m.FormatCodes) {
+				formatCode = m.FormatCodes[i]
+			}
+			var field *query.Field
+			if i < len(m.Fields) {
+				field = m.Fields[i]
+			}
+			value, err := EncodeValue(field, m.Values[i], formatCode)
+			if err != nil {
+				return connection.MessageFormat{}, err
+			}
 			outputMessage.Field("Columns").Child("ColumnLength", i).MustWrite(len(value))
 			outputMessage.Field("Columns").Child("ColumnData", i).MustWrite(value)
 		}
@@ -15,6 +15,7 @@
 package messages
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/dolthub/doltgresql/postgres/connection"
@@ -47,8 +48,12 @@ type ErrorResponse struct {
 	Optional     ErrorResponseOptionalFields
 }
 
-// ErrorResponseOptionalFields are optional fields that will not be sent if their values are empty strings.
+// ErrorResponseOptionalFields are optional fields that will not be sent if their values are empty strings. The
+// Position is the 1-based character index within the query that caused the error, and is not sent when it is zero.
 type ErrorResponseOptionalFields struct {
+	Detail     string
+	Hint       string
+	Position   int32
 	Schema     string
 	Table      string
 	Column     string
@@ -111,6 +116,21 @@ func (m ErrorResponse) Encode() (connection.MessageFormat, error) {
 
 	// Write the optional fields after the required fields
 	i := 4
+	if len(m.Optional.Detail) > 0 {
+		outputMessage.Field("Fields").Child("Code", i).MustWrite('D')
+		outputMessage.Field("Fields").Child("Value", i).MustWrite(m.Optional.Detail)
+		i++
+	}
+	if len(m.Optional.Hint) > 0 {
+		outputMessage.Field("Fields").Child("Code", i).MustWrite('H')
+		outputMessage.Field("Fields").Child("Value", i).MustWrite(m.Optional.Hint)
+		i++
+	}
+	if m.Optional.Position > 0 {
+		outputMessage.Field("Fields").Child("Code", i).MustWrite('P')
+		outputMessage.Field("Fields").Child("Value", i).MustWrite(strconv.Itoa(int(m.Optional.Position)))
+		i++
+	}
 	if len(m.Optional.Schema) > 0 {
 		outputMessage.Field("Fields").Child("Code", i).MustWrite('s')
 		outputMessage.Field("Fields").Child("Value", i).MustWrite(m.Optional.Schema)
@@ -157,6 +177,14 @@ func (m ErrorResponse) Decode(s connection.MessageFormat) (connection.Message, e
 			errorResponse.SqlStateCode = value
 		case 'M':
 			errorResponse.Message = value
+		case 'D':
+			errorResponse.Optional.Detail = value
+		case 'H':
+			errorResponse.Optional.Hint = value
+		case 'P':
+			if position, err := strconv.Atoi(value); err == nil {
+				errorResponse.Optional.Position = int32(position)
+			}
 		case 's':
 			errorResponse.Optional.Schema = value
 		case 't':
@@ -27,9 +27,11 @@ func init() {
 	connection.InitializeDefaultMessage(RowDescription{})
 }
 
-// RowDescription represents a RowDescription message intended for the client.
+// RowDescription represents a RowDescription message intended for the client. FormatCodes contains the format code of
+// each field, and fields without a format code use the text format.
 type RowDescription struct {
-	Fields []*query.Field
+	Fields      []*query.Field
+	FormatCodes []int32
 }
 
 var rowDescriptionDefault = connection.MessageFormat{
@@ -117,6 +119,9 @@ func (m RowDescription) Encode() (connection.MessageFormat, error) {
 		outputMessage.Field("Fields").Child("DataTypeObjectID", i).MustWrite(dataTypeObjectID)
 		outputMessage.Field("Fields").Child("DataTypeSize", i).MustWrite(dataTypeSize)
 		outputMessage.Field("Fields").Child("DataTypeModifier", i).MustWrite(dataTypeModifier)
+		if i < len(m.FormatCodes) {
+			outputMessage.Field("Fields").Child("FormatCode", i).MustWrite(m.FormatCodes[i])
+		}
 	}
 	return outputMessage, nil
 }
@@ -134,11 +139,24 @@ func (m RowDescription) DefaultMessage() *connection.MessageFormat {
 	return &rowDescriptionDefault
 }
 
+// BooleanColumnLength is the column length of a field that holds booleans. Booleans are stored as TINYINT(1), which
+// the engine reports just like any other TINYINT, so the server marks boolean fields with the display width as their
+// column length, as MySQL does.
+const BooleanColumnLength = 1
+
+// isBooleanField returns whether the field was marked as holding booleans.
+func isBooleanField(field *query.Field) bool {
+	return field.Type == query.Type_INT8 && field.ColumnLength == BooleanColumnLength
+}
+
 // VitessFieldToDataTypeObjectID returns a type, as defined by Vitess, into a type as defined by Postgres.
 func VitessFieldToDataTypeObjectID(field *query.Field) (int32, error) {
 	switch field.Type {
 	case query.Type_INT8:
-		return 17, nil
+		if isBooleanField(field) {
+			return 16, nil
+		}
+		return 21, nil
 	case query.Type_INT16:
 		return 21, nil
 	case query.Type_INT24:
@@ -174,7 +192,10 @@ func VitessFieldToDataTypeObjectID(field *query.Field) (int32, error) {
 func VitessFieldToDataTypeSize(field *query.Field) (int16, error) {
 	switch field.Type {
 	case query.Type_INT8:
-		return 1, nil
+		if isBooleanField(field) {
+			return 1, nil
+		}
+		return 2, nil
 	case query.Type_INT16:
 		return 2, nil
 	case query.Type_INT24:
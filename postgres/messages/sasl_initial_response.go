@@ -54,8 +54,8 @@ var sASLInitialResponseDefault = connection.MessageFormat{
 		},
 		{
 			Name: "ResponseData",
-			Type: connection.String,
-			Data: "",
+			Type: connection.ByteN,
+			Data: []byte{},
 		},
 	},
 }
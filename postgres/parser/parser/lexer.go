@@ -28,6 +28,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/cockroachdb/errors"
 
@@ -245,6 +246,8 @@ func (l *lexer) populateErrorDetails() {
 	// Output a caret indicating where the last token starts.
 	fmt.Fprintf(&buf, "%s^", strings.Repeat(" ", int(lastTok.pos)-j))
 	l.lastError = errors.WithDetail(l.lastError, buf.String())
+	// Clients draw their own caret from the position, which counts characters rather than bytes.
+	l.lastError = pgerror.WithPosition(l.lastError, utf8.RuneCountInString(l.in[:lastTok.pos])+1)
 }
 
 // SetHelp marks the "last error" field in the lexer to become a
@@ -36,6 +36,7 @@ package parser
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/cockroachdb/errors"
 
@@ -129,7 +130,9 @@ func (p *Parser) parseOneWithDepth(depth int, sql string) (Statement, error) {
 	return stmts[0], nil
 }
 
-func (p *Parser) scanOneStmt() (sql string, tokens []sqlSymType, done bool) {
+// scanOneStmt scans the next statement, returning its string along with the position at which it starts within the
+// scanned string.
+func (p *Parser) scanOneStmt() (sql string, start int, tokens []sqlSymType, done bool) {
 	var lval sqlSymType
 	tokens = p.tokBuf[:0]
 
@@ -137,7 +140,7 @@ func (p *Parser) scanOneStmt() (sql string, tokens []sqlSymType, done bool) {
 	for {
 		p.scanner.scan(&lval)
 		if lval.id == 0 {
-			return "", nil, true
+			return "", 0, nil, true
 		}
 		if lval.id != ';' {
 			break
@@ -150,12 +153,12 @@ func (p *Parser) scanOneStmt() (sql string, tokens []sqlSymType, done bool) {
 	tokens = append(tokens, lval)
 	for {
 		if lval.id == ERROR {
-			return p.scanner.in[startPos:], tokens, true
+			return p.scanner.in[startPos:], int(startPos), tokens, true
 		}
 		posBeforeScan := p.scanner.pos
 		p.scanner.scan(&lval)
 		if lval.id == 0 || lval.id == ';' {
-			return p.scanner.in[startPos:posBeforeScan], tokens, (lval.id == 0)
+			return p.scanner.in[startPos:posBeforeScan], int(startPos), tokens, (lval.id == 0)
 		}
 		lval.pos -= startPos
 		tokens = append(tokens, lval)
@@ -167,9 +170,13 @@ func (p *Parser) parseWithDepth(depth int, sql string, nakedIntType *types.T) (S
 	p.scanner.init(sql)
 	defer p.scanner.cleanup()
 	for {
-		sql, tokens, done := p.scanOneStmt()
+		sql, start, tokens, done := p.scanOneStmt()
 		stmt, err := p.parse(depth+1, sql, tokens, nakedIntType)
 		if err != nil {
+			// The position of a syntax error is relative to its statement, rather than to the whole string
+			if position := pgerror.GetPosition(err); position > 0 && start > 0 {
+				err = pgerror.WithPosition(err, position+utf8.RuneCountInString(p.scanner.in[:start]))
+			}
 			return nil, err
 		}
 		if stmt.AST != nil {
@@ -259,7 +266,7 @@ func HasMultipleStatements(sql string) bool {
 	defer p.scanner.cleanup()
 	count := 0
 	for {
-		_, _, done := p.scanOneStmt()
+		_, _, _, done := p.scanOneStmt()
 		if done {
 			break
 		}
@@ -0,0 +1,61 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgerror
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
+
+// withPosition decorates an error with the position within the query string that caused the error.
+type withPosition struct {
+	cause    error
+	position int
+}
+
+var _ error = (*withPosition)(nil)
+var _ fmt.Formatter = (*withPosition)(nil)
+var _ errors.SafeFormatter = (*withPosition)(nil)
+
+func (w *withPosition) Error() string { return w.cause.Error() }
+func (w *withPosition) Cause() error  { return w.cause }
+func (w *withPosition) Unwrap() error { return w.cause }
+
+func (w *withPosition) Format(s fmt.State, verb rune) { errors.FormatError(w, s, verb) }
+
+func (w *withPosition) SafeFormatError(p errors.Printer) (next error) {
+	if p.Detail() {
+		p.Printf("position: %d", errors.Safe(w.position))
+	}
+	return w.cause
+}
+
+// WithPosition decorates the error with the position within the query string that caused the error. Like Postgres,
+// the position is the 1-based index of a character (not a byte) within the query string.
+func WithPosition(err error, position int) error {
+	if err == nil {
+		return nil
+	}
+	return &withPosition{cause: err, position: position}
+}
+
+// GetPosition returns the position that the error was decorated with, or zero if the error does not have a position.
+func GetPosition(err error) int {
+	if w := (*withPosition)(nil); errors.As(err, &w) {
+		return w.position
+	}
+	return 0
+}
@@ -24,7 +24,13 @@
 
 package tree
 
-import "github.com/cockroachdb/errors"
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/dolthub/doltgresql/postgres/parser/lex"
+)
 
 // CopyFrom represents a COPY FROM statement.
 type CopyFrom struct {
@@ -34,10 +40,26 @@ type CopyFrom struct {
 	Options CopyOptions
 }
 
-// CopyOptions describes options for COPY execution.
+// CopyTo represents a COPY TO statement, which copies either a table or the results of a query.
+type CopyTo struct {
+	Table     TableName
+	Columns   NameList
+	Statement Statement
+	Stdout    bool
+	Options   CopyOptions
+}
+
+// CopyOptions describes options for COPY execution. The string options are nil when they were not given, so that the
+// defaults of the format are used.
 type CopyOptions struct {
 	Destination Expr
 	CopyFormat  CopyFormat
+	Delimiter   *string
+	Null        *string
+	Header      *bool
+	Quote       *string
+	Escape      *string
+	Encoding    *string
 }
 
 var _ NodeFormatter = &CopyOptions{}
@@ -61,6 +83,31 @@ func (node *CopyFrom) Format(ctx *FmtCtx) {
 	}
 }
 
+// Format implements the NodeFormatter interface.
+func (node *CopyTo) Format(ctx *FmtCtx) {
+	ctx.WriteString("COPY ")
+	if node.Statement != nil {
+		ctx.WriteString("(")
+		ctx.FormatNode(node.Statement)
+		ctx.WriteString(")")
+	} else {
+		ctx.FormatNode(&node.Table)
+		if len(node.Columns) > 0 {
+			ctx.WriteString(" (")
+			ctx.FormatNode(&node.Columns)
+			ctx.WriteString(")")
+		}
+	}
+	ctx.WriteString(" TO ")
+	if node.Stdout {
+		ctx.WriteString("STDOUT")
+	}
+	if !node.Options.IsDefault() {
+		ctx.WriteString(" WITH ")
+		ctx.FormatNode(&node.Options)
+	}
+}
+
 // Format implements the NodeFormatter interface
 func (o *CopyOptions) Format(ctx *FmtCtx) {
 	var addSep bool
@@ -83,6 +130,32 @@ func (o *CopyOptions) Format(ctx *FmtCtx) {
 		switch o.CopyFormat {
 		case CopyFormatBinary:
 			ctx.WriteString("BINARY")
+		case CopyFormatCSV:
+			ctx.WriteString("CSV")
+		}
+	}
+	for _, option := range []struct {
+		name  string
+		value *string
+	}{
+		{"DELIMITER", o.Delimiter},
+		{"NULL", o.Null},
+		{"QUOTE", o.Quote},
+		{"ESCAPE", o.Escape},
+		{"ENCODING", o.Encoding},
+	} {
+		if option.value != nil {
+			maybeAddSep()
+			ctx.WriteString(option.name)
+			ctx.WriteByte(' ')
+			lex.EncodeSQLString(&ctx.Buffer, *option.value)
+		}
+	}
+	if o.Header != nil {
+		maybeAddSep()
+		ctx.WriteString("HEADER")
+		if !*o.Header {
+			ctx.WriteString(" false")
 		}
 	}
 }
@@ -107,9 +180,87 @@ func (o *CopyOptions) CombineWith(other *CopyOptions) error {
 		}
 		o.CopyFormat = other.CopyFormat
 	}
+	for _, option := range []struct {
+		name  string
+		value **string
+		other *string
+	}{
+		{"delimiter", &o.Delimiter, other.Delimiter},
+		{"null", &o.Null, other.Null},
+		{"quote", &o.Quote, other.Quote},
+		{"escape", &o.Escape, other.Escape},
+		{"encoding", &o.Encoding, other.Encoding},
+	} {
+		if option.other != nil {
+			if *option.value != nil {
+				return errors.Newf("%s option specified multiple times", option.name)
+			}
+			*option.value = option.other
+		}
+	}
+	if other.Header != nil {
+		if o.Header != nil {
+			return errors.New("header option specified multiple times")
+		}
+		o.Header = other.Header
+	}
 	return nil
 }
 
+// NewCopyOption returns the options for a single option of the generic form, such as `FORMAT csv` within
+// `WITH (FORMAT csv, HEADER)`. Only boolean options may be given without a value.
+func NewCopyOption(name string, value string, hasValue bool) (*CopyOptions, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "format":
+		if !hasValue {
+			return nil, errors.Newf("%s requires a parameter", name)
+		}
+		switch strings.ToLower(value) {
+		case "text":
+			return &CopyOptions{}, nil
+		case "csv":
+			return &CopyOptions{CopyFormat: CopyFormatCSV}, nil
+		case "binary":
+			return &CopyOptions{CopyFormat: CopyFormatBinary}, nil
+		default:
+			return nil, errors.Newf(`COPY format "%s" not recognized`, value)
+		}
+	case "delimiter", "null", "quote", "escape", "encoding":
+		if !hasValue {
+			return nil, errors.Newf("%s requires a parameter", name)
+		}
+		options := &CopyOptions{}
+		switch name {
+		case "delimiter":
+			options.Delimiter = &value
+		case "null":
+			options.Null = &value
+		case "quote":
+			options.Quote = &value
+		case "escape":
+			options.Escape = &value
+		case "encoding":
+			options.Encoding = &value
+		}
+		return options, nil
+	case "header":
+		header := true
+		if hasValue {
+			switch strings.ToLower(value) {
+			case "true", "on", "1":
+			case "false", "off", "0":
+				header = false
+			default:
+				return nil, errors.Newf("%s requires a Boolean value", name)
+			}
+		}
+		return &CopyOptions{Header: &header}, nil
+	default:
+		return nil, errors.Newf(`option "%s" not recognized`, name)
+	}
+}
+
 // CopyFormat identifies a COPY data format.
 type CopyFormat int
 
@@ -117,4 +268,5 @@ type CopyFormat int
 const (
 	CopyFormatText CopyFormat = iota
 	CopyFormatBinary
+	CopyFormatCSV
 )
@@ -33,6 +33,8 @@
 
 package tree
 
+import "github.com/dolthub/doltgresql/postgres/parser/lex"
+
 // SetVar represents a SET or RESET statement.
 type SetVar struct {
 	Name   string
@@ -119,3 +121,46 @@ func (node *SetTracing) Format(ctx *FmtCtx) {
 	ctx.WriteString("SET TRACING = ")
 	ctx.FormatNode(&node.Values)
 }
+
+// Listen represents a LISTEN statement.
+type Listen struct {
+	ChannelName Name
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Listen) Format(ctx *FmtCtx) {
+	ctx.WriteString("LISTEN ")
+	ctx.FormatNode(&node.ChannelName)
+}
+
+// Unlisten represents an UNLISTEN statement. All is true for UNLISTEN *, which stops listening on every channel.
+type Unlisten struct {
+	ChannelName Name
+	All         bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Unlisten) Format(ctx *FmtCtx) {
+	ctx.WriteString("UNLISTEN ")
+	if node.All {
+		ctx.WriteString("*")
+	} else {
+		ctx.FormatNode(&node.ChannelName)
+	}
+}
+
+// Notify represents a NOTIFY statement. The payload is empty when it was not given.
+type Notify struct {
+	ChannelName Name
+	Payload     string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Notify) Format(ctx *FmtCtx) {
+	ctx.WriteString("NOTIFY ")
+	ctx.FormatNode(&node.ChannelName)
+	if len(node.Payload) > 0 {
+		ctx.WriteString(", ")
+		lex.EncodeSQLString(&ctx.Buffer, node.Payload)
+	}
+}
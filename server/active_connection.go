@@ -0,0 +1,286 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/types"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+const (
+	// defaultShutdownTimeout is how long the server waits for running queries to finish once it begins shutting down,
+	// unless the server was given a different timeout.
+	defaultShutdownTimeout = 30 * time.Second
+	// shutdownCancelTimeout is how long the server waits for the queries that are canceled once the shutdown timeout
+	// has passed, after which their connections are closed without waiting any further.
+	shutdownCancelTimeout = time.Second
+)
+
+var (
+	// errAdminShutdown is sent to every connection that is terminated because the server is shutting down.
+	errAdminShutdown = sqlStateError{Code: "57P01", Message: "terminating connection due to administrator command"}
+	// errShuttingDown is sent to new connections once the server has begun shutting down.
+	errShuttingDown = sqlStateError{Code: "57P03", Message: "the database system is shutting down"}
+	// errTooManyConnections is sent to new connections once the server has reached its connection limit.
+	errTooManyConnections = sqlStateError{Code: "53300", Message: "sorry, too many clients already"}
+	// errIdleInTransactionTimeout is sent to a connection that stayed idle within a transaction block for too long.
+	errIdleInTransactionTimeout = sqlStateError{Code: "25P03", Message: "terminating connection due to idle-in-transaction timeout"}
+	// errIdleSessionTimeout is sent to a connection that stayed idle outside of a transaction block for too long.
+	errIdleSessionTimeout = sqlStateError{Code: "57P05", Message: "terminating connection due to idle-session timeout"}
+)
+
+// idleTimeoutVariables are the session variables that limit how long a connection may wait for its next query. Like
+// Postgres, their values are in milliseconds, and zero disables them.
+var idleTimeoutVariables = []string{"idle_in_transaction_session_timeout", "idle_session_timeout"}
+
+func init() {
+	for _, name := range idleTimeoutVariables {
+		sql.SystemVariables.AddSystemVariables([]sql.SystemVariable{
+			{
+				Name:    name,
+				Scope:   sql.SystemVariableScope_Both,
+				Dynamic: true,
+				Type:    types.NewSystemIntType(name, 0, math.MaxInt32, false),
+				Default: int64(0),
+			},
+		})
+	}
+}
+
+// activeConnection is a connection that has finished starting up. The connection's own goroutine waits for each of
+// its client's messages through the activeConnection, so that the connection can be terminated from other goroutines
+// whenever it isn't running a query.
+type activeConnection struct {
+	conn      net.Conn
+	mysqlConn *mysql.Conn
+	closed    chan struct{}
+
+	// The following fields are protected by the Listener's connectionsMu
+	running                  bool
+	terminating              bool
+	idleTimeoutErr           error
+	idleSessionTimeout       time.Duration
+	idleInTransactionTimeout time.Duration
+}
+
+// addConnection registers the given connection, returning an error that should be sent to the client if the server
+// can't accept any more connections.
+func (l *Listener) addConnection(conn net.Conn, mysqlConn *mysql.Conn) (*activeConnection, error) {
+	l.connectionsMu.Lock()
+	defer l.connectionsMu.Unlock()
+	if l.draining {
+		return nil, errShuttingDown
+	}
+	if l.cfg.MaxConns > 0 && uint64(len(l.connections)) >= l.cfg.MaxConns {
+		return nil, errTooManyConnections
+	}
+	ac := &activeConnection{
+		conn:      conn,
+		mysqlConn: mysqlConn,
+		closed:    make(chan struct{}),
+		running:   true,
+	}
+	l.connections[mysqlConn.ConnectionID] = ac
+	return ac, nil
+}
+
+// removeConnection unregisters the given connection once it has closed.
+func (l *Listener) removeConnection(ac *activeConnection) {
+	l.connectionsMu.Lock()
+	defer l.connectionsMu.Unlock()
+	delete(l.connections, ac.mysqlConn.ConnectionID)
+	close(ac.closed)
+}
+
+// receive waits for the client's next message. When readyForQuery is true, the client has been sent a ReadyForQuery
+// with the given indicator, so the connection is idle and subject to the idle timeouts. When the connection can't
+// continue, the returned error is the one that should be sent to the client as FATAL before closing the connection.
+func (l *Listener) receive(ac *activeConnection, readyForQuery bool, indicator messages.ReadyForQueryTransactionIndicator) (connection.Message, error) {
+	l.connectionsMu.Lock()
+	if ac.terminating {
+		l.connectionsMu.Unlock()
+		return nil, errAdminShutdown
+	}
+	ac.running = false
+	ac.idleTimeoutErr = nil
+	var deadline time.Time
+	if readyForQuery {
+		if indicator == messages.ReadyForQueryTransactionIndicator_Idle && ac.idleSessionTimeout > 0 {
+			deadline = time.Now().Add(ac.idleSessionTimeout)
+			ac.idleTimeoutErr = errIdleSessionTimeout
+		} else if indicator != messages.ReadyForQueryTransactionIndicator_Idle && ac.idleInTransactionTimeout > 0 {
+			deadline = time.Now().Add(ac.idleInTransactionTimeout)
+			ac.idleTimeoutErr = errIdleInTransactionTimeout
+		}
+	}
+	err := ac.conn.SetReadDeadline(deadline)
+	l.connectionsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := connection.Receive(ac.conn)
+	l.connectionsMu.Lock()
+	defer l.connectionsMu.Unlock()
+	var netErr net.Error
+	if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+		if ac.terminating {
+			return nil, errAdminShutdown
+		}
+		if ac.idleTimeoutErr != nil {
+			return nil, ac.idleTimeoutErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Queries may read from the client, as COPY FROM does, so the deadline doesn't apply to them
+	ac.running = true
+	if err = ac.conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// isTerminating returns whether the connection with the given ID is being terminated.
+func (l *Listener) isTerminating(connectionID uint32) bool {
+	l.connectionsMu.Lock()
+	defer l.connectionsMu.Unlock()
+	ac, ok := l.connections[connectionID]
+	return ok && ac.terminating
+}
+
+// updateIdleTimeouts reads the connection's idle timeouts from its session when the given query sets either of them.
+func (l *Listener) updateIdleTimeouts(mysqlConn *mysql.Conn, query ConvertedQuery) error {
+	set, ok := query.AST.(*sqlparser.Set)
+	if !ok {
+		return nil
+	}
+	for _, expr := range set.Exprs {
+		for _, name := range idleTimeoutVariables {
+			if expr.Name != nil && strings.EqualFold(expr.Name.Name.String(), name) {
+				return l.readIdleTimeouts(mysqlConn)
+			}
+		}
+	}
+	return nil
+}
+
+// readIdleTimeouts reads the connection's idle timeouts from its session.
+func (l *Listener) readIdleTimeouts(mysqlConn *mysql.Conn) error {
+	ctx, err := l.session(mysqlConn)
+	if err != nil {
+		return err
+	}
+	timeouts := make(map[string]time.Duration, len(idleTimeoutVariables))
+	for _, name := range idleTimeoutVariables {
+		value, err := ctx.GetSessionVariable(ctx, name)
+		if err != nil {
+			return err
+		}
+		milliseconds, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("expected %s to be an integer but received %T", name, value)
+		}
+		timeouts[name] = time.Duration(milliseconds) * time.Millisecond
+	}
+	l.connectionsMu.Lock()
+	defer l.connectionsMu.Unlock()
+	if ac, ok := l.connections[mysqlConn.ConnectionID]; ok {
+		ac.idleSessionTimeout = timeouts["idle_session_timeout"]
+		ac.idleInTransactionTimeout = timeouts["idle_in_transaction_session_timeout"]
+	}
+	return nil
+}
+
+// drain terminates every connection. Idle connections are terminated immediately, while connections that are running
+// a query are terminated once it finishes. Queries that are still running after the shutdown timeout are canceled.
+// Each connection sends its client the FATAL error before it closes. This returns once every connection has closed.
+func (l *Listener) drain() {
+	l.connectionsMu.Lock()
+	l.draining = true
+	connections := make([]*activeConnection, 0, len(l.connections))
+	for _, ac := range l.connections {
+		ac.terminating = true
+		// Connections that are waiting for a message are woken up, so that they see that they're terminating
+		if !ac.running {
+			_ = ac.conn.SetReadDeadline(time.Now())
+		}
+		connections = append(connections, ac)
+	}
+	l.connectionsMu.Unlock()
+
+	timeout := time.After(l.shutdownTimeout)
+	for i, ac := range connections {
+		select {
+		case <-ac.closed:
+		case <-timeout:
+			remaining := connections[i:]
+			for _, ac = range remaining {
+				l.killQuery(ac)
+			}
+			cancelTimer := time.NewTimer(shutdownCancelTimeout)
+			defer cancelTimer.Stop()
+			for j, ac := range remaining {
+				select {
+				case <-ac.closed:
+				case <-cancelTimer.C:
+					// Every connection that's still open once the deadline has passed is closed
+					for _, ac = range remaining[j:] {
+						_ = ac.conn.Close()
+					}
+					return
+				}
+			}
+			return
+		}
+	}
+}
+
+// killQuery cancels the query that is running on the given connection, if it's still running one.
+func (l *Listener) killQuery(ac *activeConnection) {
+	// The query is killed from a separate session, as a session may only run one query at a time
+	killConn := &mysql.Conn{
+		Conn:         ac.conn,
+		ConnectionID: atomic.AddUint32(&connectionIDCounter, 1),
+	}
+	l.cfg.Handler.NewConnection(killConn)
+	defer l.cfg.Handler.ConnectionClosed(killConn)
+	_ = l.cfg.Handler.ComQuery(killConn, fmt.Sprintf("KILL QUERY %d;", ac.mysqlConn.ConnectionID),
+		func(*sqltypes.Result, bool) error {
+			return nil
+		})
+}
+
+// sendFatal sends the given error to the client as FATAL, which should be followed by closing the connection.
+func sendFatal(conn net.Conn, err error) {
+	response := errorResponse(err)
+	response.Severity = messages.ErrorResponseSeverity_Fatal
+	_ = connection.Send(conn, response)
+}
@@ -16,15 +16,46 @@ package ast
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
+	"github.com/cockroachdb/errors"
 	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
 
 	"github.com/dolthub/doltgresql/postgres/parser/parser"
 	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
 )
 
+// unsupportedConstruct matches the errors of constructs that cannot be converted yet, capturing the construct.
+var unsupportedConstruct = regexp.MustCompile(`^(.+?) (?:is|are) not yet supported`)
+
 // Convert converts a Postgres AST into a Vitess AST.
 func Convert(postgresStmt parser.Statement) (vitess.Statement, error) {
+	vitessStmt, err := convertStatement(postgresStmt)
+	if err != nil {
+		return nil, withUnsupportedHint(postgresStmt, err)
+	}
+	return vitessStmt, nil
+}
+
+// withUnsupportedHint adds a hint to errors of unsupported constructs, which names the construct along with the kind of
+// statement that it was found in. All other errors are returned as-is.
+func withUnsupportedHint(postgresStmt parser.Statement, err error) error {
+	match := unsupportedConstruct.FindStringSubmatch(err.Error())
+	if match == nil || postgresStmt.AST == nil {
+		return err
+	}
+	tag := postgresStmt.AST.StatementTag()
+	switch construct := match[1]; strings.ToLower(construct) {
+	case "the statement", "this statement", strings.ToLower(tag):
+		return errors.WithHint(err, fmt.Sprintf("%s statements are not yet supported by Doltgres.", tag))
+	default:
+		return errors.WithHint(err, fmt.Sprintf("Doltgres does not yet support %s within %s statements.", construct, tag))
+	}
+}
+
+// convertStatement converts the given Postgres statement into a Vitess AST.
+func convertStatement(postgresStmt parser.Statement) (vitess.Statement, error) {
 	switch stmt := postgresStmt.AST.(type) {
 	case *tree.AlterDatabaseOwner:
 		return nodeAlterDatabaseOwner(stmt)
@@ -74,6 +105,8 @@ func Convert(postgresStmt parser.Statement) (vitess.Statement, error) {
 		return nodeControlSchedules(stmt)
 	case *tree.CopyFrom:
 		return nodeCopyFrom(stmt)
+	case *tree.CopyTo:
+		return nodeCopyTo(stmt)
 	case *tree.CreateChangefeed:
 		return nodeCreateChangefeed(stmt)
 	case *tree.CreateDatabase:
@@ -132,6 +165,10 @@ func Convert(postgresStmt parser.Statement) (vitess.Statement, error) {
 		return nodeImport(stmt)
 	case *tree.Insert:
 		return nodeInsert(stmt)
+	case *tree.Listen:
+		return nodeListen(stmt)
+	case *tree.Notify:
+		return nodeNotify(stmt)
 	case *tree.ParenSelect:
 		return nodeParenSelect(stmt)
 	case *tree.Prepare:
@@ -266,6 +303,8 @@ func Convert(postgresStmt parser.Statement) (vitess.Statement, error) {
 		return nodeTruncate(stmt)
 	case *tree.UnionClause:
 		return nodeUnionClause(stmt)
+	case *tree.Unlisten:
+		return nodeUnlisten(stmt)
 	case *tree.Unsplit:
 		return nodeUnsplit(stmt)
 	case *tree.Update:
@@ -22,10 +22,79 @@ import (
 	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
 )
 
-// nodeCopyFrom handles *tree.CopyFrom nodes.
+// nodeCopyFrom handles *tree.CopyFrom nodes. COPY FROM streams its rows through the sub-protocol of the connection, so
+// it cannot be converted into a single statement. The server handles it using CopyFromColumns and CopyFromInsert.
 func nodeCopyFrom(node *tree.CopyFrom) (vitess.Statement, error) {
 	if node == nil {
 		return nil, nil
 	}
-	return nil, fmt.Errorf("COPY FROM is not yet supported")
+	return nil, fmt.Errorf("COPY FROM is not yet supported through the extended query protocol")
+}
+
+// CopyFromColumns returns a statement that returns no rows, but whose fields are the columns that the COPY FROM
+// statement's rows must contain, in the order that the rows contain them.
+func CopyFromColumns(node *tree.CopyFrom) (vitess.Statement, error) {
+	tableName, err := nodeTableName(&node.Table)
+	if err != nil {
+		return nil, err
+	}
+	return &vitess.Select{
+		SelectExprs: copySelectExprs(node.Columns),
+		From: vitess.TableExprs{
+			&vitess.AliasedTableExpr{
+				Expr: tableName,
+			},
+		},
+		Limit: &vitess.Limit{
+			Rowcount: vitess.NewIntVal([]byte("0")),
+		},
+	}, nil
+}
+
+// CopyFromInsert returns a statement that inserts the given rows into the COPY FROM statement's table. Each row must
+// contain a value for each of the columns returned by CopyFromColumns.
+func CopyFromInsert(node *tree.CopyFrom, rows [][]tree.Datum) (*vitess.Insert, error) {
+	tableName, err := nodeTableName(&node.Table)
+	if err != nil {
+		return nil, err
+	}
+	var columns []vitess.ColIdent
+	if len(node.Columns) > 0 {
+		columns = make([]vitess.ColIdent, len(node.Columns))
+		for i := range node.Columns {
+			columns[i] = vitess.NewColIdent(string(node.Columns[i]))
+		}
+	}
+	values := make(vitess.Values, len(rows))
+	for i, row := range rows {
+		values[i] = make(vitess.ValTuple, len(row))
+		for j, datum := range row {
+			if values[i][j], err = nodeExpr(datum); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &vitess.Insert{
+		Action:  vitess.InsertStr,
+		Table:   tableName,
+		Columns: columns,
+		Rows:    values,
+	}, nil
+}
+
+// copySelectExprs returns the expressions that select the given columns of a COPY statement, which selects every
+// column when no columns are given.
+func copySelectExprs(columns tree.NameList) vitess.SelectExprs {
+	if len(columns) == 0 {
+		return vitess.SelectExprs{&vitess.StarExpr{}}
+	}
+	selectExprs := make(vitess.SelectExprs, len(columns))
+	for i := range columns {
+		selectExprs[i] = &vitess.AliasedExpr{
+			Expr: &vitess.ColName{
+				Name: vitess.NewColIdent(string(columns[i])),
+			},
+		}
+	}
+	return selectExprs
 }
@@ -0,0 +1,56 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+
+	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+)
+
+// nodeCopyTo handles *tree.CopyTo nodes. COPY TO streams its rows through the sub-protocol of the connection, so it
+// cannot be converted into a single statement. The server handles it using CopyToSelect.
+func nodeCopyTo(node *tree.CopyTo) (vitess.Statement, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("COPY TO is not yet supported through the extended query protocol")
+}
+
+// CopyToSelect returns the statement whose rows are copied by the COPY TO statement. This is either the statement's
+// query, or a SELECT of the statement's table.
+func CopyToSelect(node *tree.CopyTo) (vitess.Statement, error) {
+	if node.Statement != nil {
+		selectStmt, ok := node.Statement.(*tree.Select)
+		if !ok {
+			return nil, fmt.Errorf("COPY TO only supports SELECT queries")
+		}
+		return nodeSelect(selectStmt)
+	}
+	tableName, err := nodeTableName(&node.Table)
+	if err != nil {
+		return nil, err
+	}
+	return &vitess.Select{
+		SelectExprs: copySelectExprs(node.Columns),
+		From: vitess.TableExprs{
+			&vitess.AliasedTableExpr{
+				Expr: tableName,
+			},
+		},
+	}, nil
+}
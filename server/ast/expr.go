@@ -15,9 +15,13 @@
 package ast
 
 import (
+	"encoding/hex"
 	"fmt"
 	"go/constant"
+	"math"
+	"strconv"
 
+	"github.com/cockroachdb/apd/v2"
 	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
 
 	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
@@ -284,25 +288,32 @@ func nodeExpr(node tree.Expr) (vitess.Expr, error) {
 			Escape:   nil, //TODO: is '\' the default in Postgres as well?
 		}, nil
 	case *tree.DArray:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		//TODO: the engine does not yet have an array type, so we use the text representation of the array
+		return vitess.NewStrVal([]byte(tree.AsStringWithFlags(node, tree.FmtPgwireText))), nil
 	case *tree.DBitArray:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DBool:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.BoolVal(*node), nil
 	case *tree.DBox2D:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DBytes:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewHexVal([]byte(hex.EncodeToString([]byte(*node)))), nil
 	case *tree.DCollatedString:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DDate:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(tree.AsStringWithFlags(node, tree.FmtBareStrings))), nil
 	case *tree.DDecimal:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		if node.Form != apd.Finite {
+			return nil, fmt.Errorf("NaN and Infinity are not yet supported")
+		}
+		return vitess.NewFloatVal([]byte(node.Decimal.String())), nil
 	case *tree.DEnum:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DFloat:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		if math.IsNaN(float64(*node)) || math.IsInf(float64(*node), 0) {
+			return nil, fmt.Errorf("NaN and Infinity are not yet supported")
+		}
+		return vitess.NewFloatVal([]byte(strconv.FormatFloat(float64(*node), 'g', -1, 64))), nil
 	case *tree.DGeography:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DGeometry:
@@ -310,29 +321,30 @@ func nodeExpr(node tree.Expr) (vitess.Expr, error) {
 	case *tree.DIPAddr:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DInt:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewIntVal([]byte(strconv.FormatInt(int64(*node), 10))), nil
 	case *tree.DInterval:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DJSON:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(node.JSON.String())), nil
 	case *tree.DOid:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DOidWrapper:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DString:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(*node)), nil
 	case *tree.DTime:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(tree.AsStringWithFlags(node, tree.FmtBareStrings))), nil
 	case *tree.DTimeTZ:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DTimestamp:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(node.Time.Format("2006-01-02 15:04:05.999999"))), nil
 	case *tree.DTimestampTZ:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		//TODO: the engine does not yet have a timestamp type that holds the time zone, so we store it as UTC
+		return vitess.NewStrVal([]byte(node.Time.UTC().Format("2006-01-02 15:04:05.999999"))), nil
 	case *tree.DTuple:
 		return nil, fmt.Errorf("the statement is not yet supported")
 	case *tree.DUuid:
-		return nil, fmt.Errorf("the statement is not yet supported")
+		return vitess.NewStrVal([]byte(node.UUID.String())), nil
 	case *tree.DefaultVal:
 		return nil, fmt.Errorf("default values are not yet supported")
 	case *tree.FuncExpr:
@@ -413,8 +425,9 @@ func nodeExpr(node tree.Expr) (vitess.Expr, error) {
 	case *tree.PartitionMinVal:
 		return nil, fmt.Errorf("MINVALUE is not yet supported")
 	case *tree.Placeholder:
-		//TODO: figure out if I can delete this
-		panic("this should probably be deleted (internal error, Placeholder)")
+		// Placeholders are converted to bind variables, which are replaced with their values once the statement has
+		// been bound. The Postgres placeholder $1 becomes the bind variable :v1.
+		return vitess.NewValArg([]byte(fmt.Sprintf(":v%d", int(node.Idx)+1))), nil
 	case *tree.RangeCond:
 		operator := vitess.BetweenStr
 		if node.Not {
@@ -523,6 +536,9 @@ func nodeExpr(node tree.Expr) (vitess.Expr, error) {
 	case nil:
 		return nil, nil
 	default:
+		if node == tree.DNull {
+			return &vitess.NullVal{}, nil
+		}
 		return nil, fmt.Errorf("unknown expression: `%T`", node)
 	}
 }
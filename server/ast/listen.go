@@ -0,0 +1,50 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+
+	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+)
+
+// nodeListen handles *tree.Listen nodes. Notifications are handled by the server rather than the engine, so the
+// statement is not converted.
+func nodeListen(node *tree.Listen) (vitess.Statement, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("LISTEN is handled by the connection rather than the engine")
+}
+
+// nodeNotify handles *tree.Notify nodes. Notifications are handled by the server rather than the engine, so the
+// statement is not converted. The pg_notify function may be used instead, which also accepts parameters.
+func nodeNotify(node *tree.Notify) (vitess.Statement, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("NOTIFY is handled by the connection rather than the engine")
+}
+
+// nodeUnlisten handles *tree.Unlisten nodes. Notifications are handled by the server rather than the engine, so the
+// statement is not converted.
+func nodeUnlisten(node *tree.Unlisten) (vitess.Statement, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("UNLISTEN is handled by the connection rather than the engine")
+}
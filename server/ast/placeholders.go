@@ -0,0 +1,177 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ast
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/cockroachdb/apd/v2"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
+
+	"github.com/dolthub/doltgresql/postgres/parser/parser"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+	"github.com/dolthub/doltgresql/postgres/parser/types"
+)
+
+// PlaceholderTypes returns the type of each placeholder in the given statement. The type hints are the types given by
+// the client, and always take precedence. Placeholders without a hint have their type inferred from the casts and type
+// annotations that are applied to them. Placeholders whose type could not be determined have a nil type.
+func PlaceholderTypes(stmt parser.Statement, typeHints tree.PlaceholderTypes) (tree.PlaceholderTypes, error) {
+	if len(typeHints) < stmt.NumPlaceholders {
+		paddedHints := make(tree.PlaceholderTypes, stmt.NumPlaceholders)
+		copy(paddedHints, typeHints)
+		typeHints = paddedHints
+	}
+	var info tree.PlaceholderInfo
+	if err := info.Init(stmt.NumPlaceholders, typeHints); err != nil {
+		return nil, err
+	}
+	err := walkReflect(reflect.ValueOf(stmt.AST), func(val reflect.Value) (bool, error) {
+		if !val.CanInterface() {
+			return false, nil
+		}
+		var expr tree.Expr
+		var typ tree.ResolvableTypeReference
+		switch node := val.Interface().(type) {
+		case *tree.CastExpr:
+			expr, typ = node.Expr, node.Type
+		case *tree.AnnotateTypeExpr:
+			expr, typ = node.Expr, node.Type
+		default:
+			return true, nil
+		}
+		placeholder, ok := tree.StripParens(expr).(*tree.Placeholder)
+		if !ok {
+			return true, nil
+		}
+		resolvedType, ok := typ.(*types.T)
+		if !ok || int(placeholder.Idx) >= len(info.Types) {
+			return true, nil
+		}
+		return true, info.SetType(placeholder.Idx, resolvedType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	placeholderTypes := make(tree.PlaceholderTypes, stmt.NumPlaceholders)
+	for i := range placeholderTypes {
+		placeholderTypes[i], _ = info.ValueType(tree.PlaceholderIdx(i))
+	}
+	return placeholderTypes, nil
+}
+
+// BindVariables returns the bind variables that hold the given values. A placeholder such as $1 is converted to the
+// bind variable v1, which is given the value at index 0. The engine substitutes the bind variables once it binds the
+// converted statement, so the statement itself is never modified.
+func BindVariables(values []tree.Datum) (map[string]*query.BindVariable, error) {
+	bindVariables := make(map[string]*query.BindVariable, len(values))
+	for i, value := range values {
+		bindVariable, err := datumBindVariable(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for parameter $%d: %w", i+1, err)
+		}
+		bindVariables[fmt.Sprintf("v%d", i+1)] = bindVariable
+	}
+	return bindVariables, nil
+}
+
+// datumBindVariable returns the bind variable that holds the given value. Values are represented the same way as
+// they are when they're given as literals within a statement.
+func datumBindVariable(datum tree.Datum) (*query.BindVariable, error) {
+	if datum == tree.DNull {
+		return sqltypes.NullBindVariable, nil
+	}
+	switch datum := datum.(type) {
+	case *tree.DArray:
+		//TODO: the engine does not yet have an array type, so we use the text representation of the array
+		return sqltypes.StringBindVariable(tree.AsStringWithFlags(datum, tree.FmtPgwireText)), nil
+	case *tree.DBool:
+		if *datum {
+			return sqltypes.Int8BindVariable(1), nil
+		}
+		return sqltypes.Int8BindVariable(0), nil
+	case *tree.DBytes:
+		return sqltypes.BytesBindVariable([]byte(*datum)), nil
+	case *tree.DDate:
+		return sqltypes.StringBindVariable(tree.AsStringWithFlags(datum, tree.FmtBareStrings)), nil
+	case *tree.DDecimal:
+		if datum.Form != apd.Finite {
+			return nil, fmt.Errorf("NaN and Infinity are not yet supported")
+		}
+		return sqltypes.ValueBindVariable(sqltypes.MakeTrusted(sqltypes.Decimal, []byte(datum.Decimal.String()))), nil
+	case *tree.DFloat:
+		if math.IsNaN(float64(*datum)) || math.IsInf(float64(*datum), 0) {
+			return nil, fmt.Errorf("NaN and Infinity are not yet supported")
+		}
+		return sqltypes.Float64BindVariable(float64(*datum)), nil
+	case *tree.DInt:
+		return sqltypes.Int64BindVariable(int64(*datum)), nil
+	case *tree.DJSON:
+		return sqltypes.StringBindVariable(datum.JSON.String()), nil
+	case *tree.DString:
+		return sqltypes.StringBindVariable(string(*datum)), nil
+	case *tree.DTime:
+		return sqltypes.StringBindVariable(tree.AsStringWithFlags(datum, tree.FmtBareStrings)), nil
+	case *tree.DTimestamp:
+		return sqltypes.StringBindVariable(datum.Time.Format("2006-01-02 15:04:05.999999")), nil
+	case *tree.DTimestampTZ:
+		//TODO: the engine does not yet have a timestamp type that holds the time zone, so we store it as UTC
+		return sqltypes.StringBindVariable(datum.Time.UTC().Format("2006-01-02 15:04:05.999999")), nil
+	case *tree.DUuid:
+		return sqltypes.StringBindVariable(datum.UUID.String()), nil
+	default:
+		return nil, fmt.Errorf("parameters of type %s are not yet supported", datum.ResolvedType().SQLStandardName())
+	}
+}
+
+// walkReflect calls the given function for the given value, and then for every value that is reachable through
+// pointers, interfaces, slices, arrays, and exported struct fields. Returning false from the function skips the values
+// that are reachable from the current value. This allows us to find (and replace) specific nodes without having to
+// write a walker for every node type of an AST.
+func walkReflect(val reflect.Value, fn func(reflect.Value) (bool, error)) error {
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface, reflect.Pointer, reflect.Slice:
+		if val.IsNil() {
+			return nil
+		}
+	}
+	if recurse, err := fn(val); err != nil || !recurse {
+		return err
+	}
+	switch val.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return walkReflect(val.Elem(), fn)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := walkReflect(val.Index(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).IsExported() {
+				if err := walkReflect(val.Field(i), fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -16,17 +16,47 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
 
 	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
 )
 
-// nodeSetVar handles *tree.SetVar nodes.
+// nodeSetVar handles *tree.SetVar nodes. Configuration parameters are session variables in the engine.
 func nodeSetVar(node *tree.SetVar) (vitess.Statement, error) {
 	if node == nil {
 		return nil, nil
 	}
-	//TODO: For some reason, the parser does not actually implement SET, so we need to add that
-	return nil, fmt.Errorf("SET is not yet supported")
+	if len(node.Name) == 0 {
+		return nil, fmt.Errorf("SET ROW is not yet supported")
+	}
+	if len(node.Values) != 1 {
+		return nil, fmt.Errorf("SET with multiple values is not yet supported")
+	}
+	var expr vitess.Expr
+	switch value := node.Values[0].(type) {
+	case tree.DefaultVal:
+		expr = &vitess.Default{}
+	case *tree.UnresolvedName:
+		// Values may be given as identifiers, which are treated the same as strings
+		if value.NumParts != 1 || value.Star {
+			return nil, fmt.Errorf("invalid value for parameter \"%s\": \"%s\"", node.Name, value.String())
+		}
+		expr = vitess.NewStrVal([]byte(value.Parts[0]))
+	default:
+		var err error
+		if expr, err = nodeExpr(value); err != nil {
+			return nil, err
+		}
+	}
+	return &vitess.Set{
+		Exprs: vitess.SetVarExprs{
+			{
+				Scope: vitess.SetScope_Session,
+				Name:  &vitess.ColName{Name: vitess.NewColIdent(strings.ToLower(node.Name))},
+				Expr:  expr,
+			},
+		},
+	}, nil
 }
@@ -16,16 +16,29 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
 
 	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
 )
 
-// nodeShowVar handles *tree.ShowVar nodes.
+// nodeShowVar handles *tree.ShowVar nodes. Configuration parameters are session variables in the engine, so this
+// selects the variable's value in a column with the parameter's name.
 func nodeShowVar(node *tree.ShowVar) (vitess.Statement, error) {
 	if node == nil {
 		return nil, nil
 	}
-	return nil, fmt.Errorf("SHOW is not yet supported")
+	name := strings.ToLower(node.Name)
+	if name == "all" {
+		return nil, fmt.Errorf("SHOW ALL is not yet supported")
+	}
+	return &vitess.Select{
+		SelectExprs: vitess.SelectExprs{
+			&vitess.AliasedExpr{
+				Expr: &vitess.ColName{Name: vitess.NewColIdent("@@session." + name)},
+				As:   vitess.NewColIdent(name),
+			},
+		},
+	}, nil
 }
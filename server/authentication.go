@@ -0,0 +1,352 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// mockScramSecret is used to create consistent verifiers for users that do not exist, so that clients cannot determine
+// which users exist by the responses that they receive.
+var mockScramSecret = func() []byte {
+	secret := make([]byte, sha256.Size)
+	_, _ = rand.Read(secret)
+	return secret
+}()
+
+// loadAuthFile reads the password verifiers from the auth file at the given path. The file uses the same format as
+// PgBouncer's auth_file, where each line contains a user's name followed by their password, with both in double
+// quotes. A double quote within a value is written as two double quotes. The password should be a SCRAM-SHA-256
+// verifier, such as the ones that Postgres stores in pg_authid. A plain password is also accepted, in which case a
+// verifier is created for it as the file is loaded. Empty lines and lines starting with ; or # are ignored.
+func loadAuthFile(path string) (map[string]scramVerifier, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	verifiers := make(map[string]scramVerifier)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		values, err := parseQuotedValues(line)
+		if err != nil || len(values) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected a quoted user name followed by a quoted password", path, lineNumber)
+		}
+		user, password := values[0], values[1]
+		var verifier scramVerifier
+		if strings.HasPrefix(password, scramMechanism+"$") {
+			verifier, err = parseScramVerifier(password)
+		} else if strings.HasPrefix(password, "md5") && len(password) == 35 {
+			err = fmt.Errorf("MD5 passwords are not supported, use a SCRAM-SHA-256 verifier instead")
+		} else {
+			verifier, err = newScramVerifier(password)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		verifiers[user] = verifier
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return verifiers, nil
+}
+
+// parseQuotedValues returns the double-quoted values within the given line, which are separated by whitespace.
+func parseQuotedValues(line string) ([]string, error) {
+	var values []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if len(line) == 0 {
+			return values, nil
+		}
+		if line[0] != '"' {
+			return nil, fmt.Errorf("expected a double quote")
+		}
+		value := strings.Builder{}
+		i := 1
+		for ; i < len(line); i++ {
+			if line[i] == '"' {
+				if i+1 < len(line) && line[i+1] == '"' {
+					value.WriteByte('"')
+					i++
+					continue
+				}
+				break
+			}
+			value.WriteByte(line[i])
+		}
+		if i >= len(line) {
+			return nil, fmt.Errorf("unterminated double quote")
+		}
+		values = append(values, value.String())
+		line = line[i+1:]
+	}
+}
+
+// authenticate authenticates the given user, ending with the AuthenticationOk message once the client has proven that
+// it knows the user's password. The method is determined by the first HBA rule that matches the connection. Without
+// an HBA file, every connection is authenticated using SCRAM-SHA-256 when an auth file was given, and every connection
+// is trusted otherwise. When authentication fails, the error has already been sent to the client, and the connection
+// should be closed.
+func (l *Listener) authenticate(conn net.Conn, user string, database string) error {
+	method := hbaMethod_Trust
+	if l.hbaRules != nil {
+		rule, ok := findHBARule(l.hbaRules, conn, user, database)
+		if !ok {
+			return sendAuthenticationError(conn, hbaError(conn, user, database, false))
+		}
+		method = rule.Method
+	} else if l.passwordVerifiers != nil {
+		method = hbaMethod_ScramSHA256
+	}
+
+	var err error
+	switch method {
+	case hbaMethod_Trust:
+	case hbaMethod_Reject:
+		err = hbaError(conn, user, database, true)
+	case hbaMethod_Password:
+		err = l.authenticatePassword(conn, user)
+	case hbaMethod_MD5, hbaMethod_ScramSHA256:
+		// Like Postgres, md5 uses SCRAM-SHA-256 when the user's password is stored as a SCRAM-SHA-256 verifier, which
+		// is always the case here
+		err = l.authenticateScram(conn, user)
+	case hbaMethod_Certificate:
+		err = l.authenticateCertificate(conn, user)
+	default:
+		err = fmt.Errorf("unknown authentication method: %s", method)
+	}
+	if err != nil {
+		return sendAuthenticationError(conn, err)
+	}
+	return connection.Send(conn, messages.AuthenticationOk{})
+}
+
+// sendAuthenticationError sends the given authentication error to the client, and then returns the same error.
+func sendAuthenticationError(conn net.Conn, err error) error {
+	_ = connection.Send(conn, messages.ErrorResponse{
+		Severity:     messages.ErrorResponseSeverity_Fatal,
+		SqlStateCode: errorSQLState(err),
+		Message:      err.Error(),
+		Optional: messages.ErrorResponseOptionalFields{
+			Routine: "auth_failed",
+		},
+	})
+	return err
+}
+
+// authenticatePassword asks the client for the user's password in clear text, which is then checked against the
+// user's verifier. This should only be used over TLS, as anyone observing the connection will see the password.
+func (l *Listener) authenticatePassword(conn net.Conn, user string) error {
+	if err := connection.Send(conn, messages.AuthenticationCleartextPassword{}); err != nil {
+		return err
+	}
+	passwordMessage, err := connection.ReceiveInto(conn, messages.PasswordMessage{})
+	if err != nil {
+		return sqlStateError{
+			Code:    "08P01",
+			Message: fmt.Sprintf("expected password response: %s", err.Error()),
+		}
+	}
+	verifier, userExists := l.passwordVerifiers[user]
+	if !userExists || !verifier.VerifyPassword(passwordMessage.Password) {
+		return passwordAuthenticationError(user)
+	}
+	return nil
+}
+
+// authenticateCertificate checks that the client presented a verified certificate whose common name is the user.
+func (l *Listener) authenticateCertificate(conn net.Conn, user string) error {
+	tlsConn, ok := asTLSConn(conn)
+	if !ok || len(tlsConn.ConnectionState().VerifiedChains) == 0 {
+		return sqlStateError{
+			Code:    "28000",
+			Message: "connection requires a valid client certificate",
+		}
+	}
+	if tlsConn.ConnectionState().VerifiedChains[0][0].Subject.CommonName != user {
+		return sqlStateError{
+			Code:    "28000",
+			Message: fmt.Sprintf(`certificate authentication failed for user "%s"`, user),
+		}
+	}
+	return nil
+}
+
+// authenticateScram performs the SCRAM-SHA-256 exchange, as described by RFC 5802 and RFC 7677. Over TLS, the client
+// may also bind the exchange to the TLS connection (SCRAM-SHA-256-PLUS), which prevents a man in the middle from
+// relaying the exchange.
+func (l *Listener) authenticateScram(conn net.Conn, user string) error {
+	_, isTLS := asTLSConn(conn)
+	mechanisms := []string{scramMechanism}
+	if isTLS {
+		mechanisms = []string{scramPlusMechanism, scramMechanism}
+	}
+	if err := connection.Send(conn, messages.AuthenticationSASL{Mechanisms: mechanisms}); err != nil {
+		return err
+	}
+	initialResponse, err := connection.ReceiveInto(conn, messages.SASLInitialResponse{})
+	if err != nil {
+		return scramProtocolError("expected SASL response: %s", err.Error())
+	}
+	usesPlus := initialResponse.Name == scramPlusMechanism
+	if initialResponse.Name != scramMechanism && !(usesPlus && isTLS) {
+		return scramProtocolError("client selected an invalid SASL authentication mechanism")
+	}
+
+	// The client-first-message is the GS2 header, followed by the bare message that contains the user and nonce
+	clientFirstMessage := string(initialResponse.Response)
+	channelBindingFlag, remaining, _ := strings.Cut(clientFirstMessage, ",")
+	authorizationID, clientFirstMessageBare, ok := strings.Cut(remaining, ",")
+	if !ok {
+		return scramProtocolError("malformed SCRAM message: %q", clientFirstMessage)
+	}
+	gs2Header := clientFirstMessage[:len(clientFirstMessage)-len(clientFirstMessageBare)]
+	switch channelBindingFlag {
+	case "p=" + scramChannelBinding:
+		if !usesPlus {
+			return scramProtocolError("channel binding was requested, but the client selected a mechanism without channel binding")
+		}
+	case "n", "y":
+		if usesPlus {
+			return scramProtocolError("the client selected a mechanism with channel binding, but did not send channel binding data")
+		}
+		// A client that supports channel binding, but believes that the server does not, may have been downgraded
+		if channelBindingFlag == "y" && isTLS {
+			return scramProtocolError("SCRAM channel binding negotiation error")
+		}
+	default:
+		if strings.HasPrefix(channelBindingFlag, "p=") {
+			return scramProtocolError("unsupported SCRAM channel binding type %q", channelBindingFlag[2:])
+		}
+		return scramProtocolError("malformed SCRAM message: %q", clientFirstMessage)
+	}
+	if len(authorizationID) > 0 {
+		return scramProtocolError("client uses authorization identity, but it is not supported")
+	}
+	// The user within the message is ignored, as the user was given in the startup message
+	attributes, err := scramAttributes(clientFirstMessageBare)
+	if err != nil {
+		return scramProtocolError("%s", err.Error())
+	}
+	if attributes[0][0] == "m" {
+		return scramProtocolError("client requires an unsupported SCRAM extension")
+	}
+	if len(attributes) < 2 || attributes[0][0] != "n" || attributes[1][0] != "r" || len(attributes[1][1]) == 0 {
+		return scramProtocolError("malformed SCRAM message: %q", clientFirstMessage)
+	}
+
+	verifier, userExists := l.passwordVerifiers[user]
+	if !userExists {
+		verifier = mockScramVerifier(user)
+	}
+	serverNonce, err := scramNonce()
+	if err != nil {
+		return err
+	}
+	nonce := attributes[1][1] + serverNonce
+	serverFirstMessage := fmt.Sprintf("r=%s,s=%s,i=%d", nonce, base64.StdEncoding.EncodeToString(verifier.Salt), verifier.Iterations)
+	if err = connection.Send(conn, messages.AuthenticationSASLContinue{Data: []byte(serverFirstMessage)}); err != nil {
+		return err
+	}
+
+	// The client-final-message contains the channel binding, the nonce, and finally the client's proof
+	response, err := connection.ReceiveInto(conn, messages.SASLResponse{})
+	if err != nil {
+		return scramProtocolError("expected SASL response: %s", err.Error())
+	}
+	clientFinalMessage := string(response.Data)
+	proofIndex := strings.LastIndex(clientFinalMessage, ",p=")
+	if proofIndex == -1 {
+		return scramProtocolError("malformed SCRAM message: %q", clientFinalMessage)
+	}
+	clientFinalMessageWithoutProof := clientFinalMessage[:proofIndex]
+	proof, err := base64.StdEncoding.DecodeString(clientFinalMessage[proofIndex+3:])
+	if err != nil {
+		return scramProtocolError("malformed SCRAM message: %q", clientFinalMessage)
+	}
+	attributes, err = scramAttributes(clientFinalMessageWithoutProof)
+	if err != nil {
+		return scramProtocolError("%s", err.Error())
+	}
+	if len(attributes) < 2 || attributes[0][0] != "c" || attributes[1][0] != "r" {
+		return scramProtocolError("malformed SCRAM message: %q", clientFinalMessage)
+	}
+	channelBinding := []byte(gs2Header)
+	if usesPlus {
+		bindingData, err := scramChannelBindingData(l.ssl.certificate())
+		if err != nil {
+			return err
+		}
+		channelBinding = append(channelBinding, bindingData...)
+	}
+	if attributes[0][1] != base64.StdEncoding.EncodeToString(channelBinding) {
+		return scramProtocolError("SCRAM channel binding check failed")
+	}
+	if attributes[1][1] != nonce {
+		return scramProtocolError("SCRAM nonce mismatch")
+	}
+
+	authMessage := clientFirstMessageBare + "," + serverFirstMessage + "," + clientFinalMessageWithoutProof
+	if !userExists || !verifier.VerifyProof(authMessage, proof) {
+		return passwordAuthenticationError(user)
+	}
+	serverFinalMessage := "v=" + base64.StdEncoding.EncodeToString(verifier.ServerSignature(authMessage))
+	return connection.Send(conn, messages.AuthenticationSASLFinal{AdditionalData: []byte(serverFinalMessage)})
+}
+
+// mockScramVerifier returns a verifier for a user that does not exist. The salt is the same for every connection as
+// the given user, so that it looks like the salt of a real user. No password will match the verifier.
+func mockScramVerifier(user string) scramVerifier {
+	salt := sha256.Sum256(append([]byte(user), mockScramSecret...))
+	return scramVerifier{
+		Iterations: scramIterations,
+		Salt:       salt[:scramSaltLength],
+		StoredKey:  mockScramSecret,
+		ServerKey:  mockScramSecret,
+	}
+}
+
+// passwordAuthenticationError returns the error for a client that did not prove that it knows the user's password.
+func passwordAuthenticationError(user string) error {
+	return sqlStateError{
+		Code:    "28P01",
+		Message: fmt.Sprintf(`password authentication failed for user "%s"`, user),
+	}
+}
+
+// scramProtocolError returns an error for a client that did not follow the SCRAM exchange.
+func scramProtocolError(format string, args ...any) error {
+	return sqlStateError{
+		Code:    "08P01",
+		Message: fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/planbuilder"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
+	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// booleanColumns returns which of the statement's result columns hold booleans. Booleans are stored as TINYINT(1),
+// and the engine's result fields do not include the display width that sets them apart from any other TINYINT, so the
+// statement is bound to find the types of its columns. Only statements that select rows are bound, and nil is returned
+// when the statement cannot be bound, which leaves every column described by its field.
+func booleanColumns(mysqlConn *mysql.Conn, statement vitess.Statement, query string) []bool {
+	if _, ok := statement.(vitess.SelectStatement); !ok {
+		return nil
+	}
+	runningServer, _ := sqlserver.GetRunningServer()
+	if runningServer == nil {
+		return nil
+	}
+	ctx, err := runningServer.SessionManager().NewContextWithQuery(mysqlConn, query)
+	if err != nil {
+		return nil
+	}
+	node, err := planbuilder.New(ctx, runningServer.Engine.Analyzer.Catalog).BindOnly(statement, query)
+	if err != nil {
+		return nil
+	}
+	schema := node.Schema()
+	booleans := make([]bool, len(schema))
+	hasBooleans := false
+	for i, column := range schema {
+		if numberType, ok := column.Type.(sql.NumberType); ok && numberType.Type() == sqltypes.Int8 && numberType.DisplayWidth() == 1 {
+			booleans[i] = true
+			hasBooleans = true
+		}
+	}
+	if !hasBooleans {
+		return nil
+	}
+	return booleans
+}
+
+// markBooleanFields marks the fields of the given boolean columns, so that they are described as booleans rather than
+// as integers.
+func markBooleanFields(fields []*query.Field, booleans []bool) {
+	if len(fields) != len(booleans) {
+		return
+	}
+	for i, field := range fields {
+		if booleans[i] && field.Type == query.Type_INT8 {
+			field.ColumnLength = messages.BooleanColumnLength
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// errQueryCanceled is returned for a query that failed due to a CancelRequest.
+var errQueryCanceled = sqlStateError{Code: "57014", Message: "canceling statement due to user request"}
+
+// backendKeys contains the key of each live connection, keyed by the connection's process ID. The process ID is the
+// connection's ID, so that it is unique for each connection. A CancelRequest must provide the process ID and the secret
+// key, so that clients cannot cancel the queries of other clients.
+var backendKeys = struct {
+	sync.Mutex
+	keys map[int32]*backendKey
+}{keys: make(map[int32]*backendKey)}
+
+// backendKey is the key of a single connection.
+type backendKey struct {
+	SecretKey int32
+
+	// The following fields are protected by mu, which is also held while a CancelRequest kills the connection's query.
+	// Canceled is set when a CancelRequest was accepted, so that the query's error may be reported as such, while
+	// QuerySequence is incremented as each query begins, so that a CancelRequest can't kill a later query.
+	mu            sync.Mutex
+	Canceled      bool
+	QuerySequence uint64
+}
+
+// getBackendKey returns the key of the connection with the given process ID, or nil if the connection isn't live.
+func getBackendKey(processID int32) *backendKey {
+	backendKeys.Lock()
+	defer backendKeys.Unlock()
+	return backendKeys.keys[processID]
+}
+
+// newBackendKey creates and registers a random secret key for the connection with the given process ID.
+func newBackendKey(processID int32) (int32, error) {
+	secretKeyBytes := make([]byte, 4)
+	if _, err := rand.Read(secretKeyBytes); err != nil {
+		return 0, err
+	}
+	secretKey := int32(binary.BigEndian.Uint32(secretKeyBytes))
+	backendKeys.Lock()
+	defer backendKeys.Unlock()
+	backendKeys.keys[processID] = &backendKey{SecretKey: secretKey}
+	return secretKey, nil
+}
+
+// removeBackendKey removes the key of the connection with the given process ID, so that it can no longer be the
+// target of a CancelRequest.
+func removeBackendKey(processID int32) {
+	backendKeys.Lock()
+	defer backendKeys.Unlock()
+	delete(backendKeys.keys, processID)
+}
+
+// beginQuery marks the start of a new query on the connection with the given process ID. A CancelRequest that arrived
+// between queries does not affect the new query, and one that is still killing the previous query finishes first.
+func beginQuery(processID int32) {
+	key := getBackendKey(processID)
+	if key == nil {
+		return
+	}
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	key.QuerySequence++
+	key.Canceled = false
+}
+
+// takeCanceled returns whether a CancelRequest was accepted for the connection with the given process ID since the
+// last call, clearing it for the next query.
+func takeCanceled(processID int32) bool {
+	key := getBackendKey(processID)
+	if key == nil {
+		return false
+	}
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	canceled := key.Canceled
+	key.Canceled = false
+	return canceled
+}
+
+// cancelQuery cancels the query that is running on the connection targeted by the given request. The request is
+// ignored if it does not match a live connection. Like Postgres, nothing is sent back to the client in either case,
+// as the canceled query's connection will receive the error. The given connection is the one that sent the request.
+func (l *Listener) cancelQuery(mysqlConn *mysql.Conn, request messages.CancelRequest) error {
+	key := getBackendKey(request.ProcessID)
+	if key == nil || subtle.ConstantTimeEq(key.SecretKey, request.SecretKey) != 1 {
+		return nil
+	}
+	// The query that was running when the request was received is the only one that the request may cancel
+	key.mu.Lock()
+	sequence := key.QuerySequence
+	key.mu.Unlock()
+
+	// KILL QUERY kills whichever query the connection is running, so the connection must not begin another query until
+	// the kill has finished. Another request may be killing the query while this one waits, so the connection may have
+	// begun a later query by the time this request has the lock, in which case this request is ignored.
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if key.QuerySequence != sequence {
+		return nil
+	}
+	key.Canceled = true
+	return l.cfg.Handler.ComQuery(mysqlConn, fmt.Sprintf("KILL QUERY %d;", uint32(request.ProcessID)),
+		func(*sqltypes.Result, bool) error {
+			return nil
+		})
+}
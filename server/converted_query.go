@@ -14,13 +14,18 @@
 
 package server
 
-import vitess "github.com/dolthub/vitess/go/vt/sqlparser"
+import (
+	"github.com/dolthub/vitess/go/vt/proto/query"
+	vitess "github.com/dolthub/vitess/go/vt/sqlparser"
+)
 
 // ConvertedQuery represents a query that has been converted from the Postgres representation to the Vitess
 // representation. String may contain the string version of the converted query. AST will contain the tree
 // version of the converted query, and is the recommended form to use. If AST is nil, then use the String version,
-// otherwise always prefer to AST.
+// otherwise always prefer to AST. BindVariables contains the values of the AST's bind variables, once its parameters
+// have been bound.
 type ConvertedQuery struct {
-	String string
-	AST    vitess.Statement
+	String        string
+	AST           vitess.Statement
+	BindVariables map[string]*query.BindVariable
 }
@@ -0,0 +1,859 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+	"github.com/lib/pq/oid"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+	"github.com/dolthub/doltgresql/server/ast"
+)
+
+// copyBatchSize is the number of rows that are inserted by each statement that COPY FROM runs.
+const copyBatchSize = 1000
+
+// copyBinarySignature is the signature that begins the header of the binary format.
+const copyBinarySignature = "PGCOPY\n\xff\r\n\x00"
+
+// copyEndOfData is the line that may end the data of COPY FROM, rather than ending it with the CopyDone message.
+const copyEndOfData = `\.`
+
+// copyOptions are the options of a COPY statement, with the defaults of the format applied.
+type copyOptions struct {
+	Format    tree.CopyFormat
+	Delimiter byte
+	Null      string
+	Header    bool
+	Quote     byte
+	Escape    byte
+}
+
+// newCopyOptions validates the given options, and returns them with the defaults of their format applied.
+func newCopyOptions(options tree.CopyOptions) (copyOptions, error) {
+	if options.Destination != nil {
+		return copyOptions{}, sqlStateError{Code: "0A000", Message: "COPY destination is not supported"}
+	}
+	resolved := copyOptions{
+		Format:    options.CopyFormat,
+		Delimiter: '\t',
+		Null:      `\N`,
+		Quote:     '"',
+	}
+	switch options.CopyFormat {
+	case tree.CopyFormatText:
+		if options.Quote != nil {
+			return copyOptions{}, sqlStateError{Code: "0A000", Message: "COPY quote available only in CSV mode"}
+		}
+		if options.Escape != nil {
+			return copyOptions{}, sqlStateError{Code: "0A000", Message: "COPY escape available only in CSV mode"}
+		}
+	case tree.CopyFormatCSV:
+		resolved.Delimiter = ','
+		resolved.Null = ""
+	case tree.CopyFormatBinary:
+		for _, option := range []struct {
+			name  string
+			given bool
+		}{
+			{"DELIMITER", options.Delimiter != nil},
+			{"NULL", options.Null != nil},
+			{"HEADER", options.Header != nil},
+			{"QUOTE", options.Quote != nil},
+			{"ESCAPE", options.Escape != nil},
+		} {
+			if option.given {
+				return copyOptions{}, sqlStateError{Code: "42601", Message: fmt.Sprintf("cannot specify %s in BINARY mode", option.name)}
+			}
+		}
+	}
+
+	singleByte := func(name string, value string) (byte, error) {
+		if len(value) != 1 {
+			return 0, sqlStateError{Code: "0A000", Message: fmt.Sprintf("COPY %s must be a single one-byte character", name)}
+		}
+		return value[0], nil
+	}
+	var err error
+	if options.Delimiter != nil {
+		if resolved.Delimiter, err = singleByte("delimiter", *options.Delimiter); err != nil {
+			return copyOptions{}, err
+		}
+	}
+	if options.Null != nil {
+		resolved.Null = *options.Null
+	}
+	if options.Header != nil {
+		resolved.Header = *options.Header
+	}
+	if options.Quote != nil {
+		if resolved.Quote, err = singleByte("quote", *options.Quote); err != nil {
+			return copyOptions{}, err
+		}
+	}
+	resolved.Escape = resolved.Quote
+	if options.Escape != nil {
+		if resolved.Escape, err = singleByte("escape", *options.Escape); err != nil {
+			return copyOptions{}, err
+		}
+	}
+	if options.Encoding != nil {
+		switch strings.ToUpper(strings.ReplaceAll(*options.Encoding, "-", "")) {
+		case "UTF8", "UNICODE":
+		default:
+			return copyOptions{}, sqlStateError{Code: "0A000", Message: fmt.Sprintf(`encoding "%s" is not supported`, *options.Encoding)}
+		}
+	}
+
+	if resolved.Delimiter == '\n' || resolved.Delimiter == '\r' {
+		return copyOptions{}, sqlStateError{Code: "22023", Message: "COPY delimiter cannot be newline or carriage return"}
+	}
+	if strings.ContainsAny(resolved.Null, "\r\n") {
+		return copyOptions{}, sqlStateError{Code: "22023", Message: "COPY null representation cannot use newline or carriage return"}
+	}
+	if resolved.Format == tree.CopyFormatText && resolved.Delimiter == '\\' {
+		return copyOptions{}, sqlStateError{Code: "22023", Message: `COPY delimiter cannot be "\"`}
+	}
+	if resolved.Format == tree.CopyFormatCSV && resolved.Delimiter == resolved.Quote {
+		return copyOptions{}, sqlStateError{Code: "22023", Message: "COPY delimiter and quote must be different"}
+	}
+	if strings.IndexByte(resolved.Null, resolved.Delimiter) != -1 {
+		return copyOptions{}, sqlStateError{Code: "22023", Message: "COPY delimiter must not appear in the NULL specification"}
+	}
+	return resolved, nil
+}
+
+// copyFormatError returns an error for data of COPY FROM that does not match its format.
+func copyFormatError(format string, args ...any) error {
+	return sqlStateError{Code: "22P04", Message: fmt.Sprintf(format, args...)}
+}
+
+// copyInReader reads the data that the client sends through CopyData messages while the connection is in the copy-in
+// mode. The data ends with the CopyDone message, which is returned as io.EOF, while a CopyFail message returns an
+// error.
+type copyInReader struct {
+	conn net.Conn
+	data []byte
+	done bool
+}
+
+var _ io.Reader = (*copyInReader)(nil)
+
+// Read implements the interface io.Reader.
+func (r *copyInReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		message, err := connection.Receive(r.conn)
+		if err != nil {
+			return 0, err
+		}
+		switch message := message.(type) {
+		case messages.CopyData:
+			r.data = message.Data
+		case messages.CopyDone:
+			r.done = true
+		case messages.CopyFail:
+			r.done = true
+			return 0, sqlStateError{Code: "57014", Message: fmt.Sprintf("COPY from stdin failed: %s", message.ErrorMessage)}
+		case messages.Flush, messages.Sync:
+			// Clients may send these during the copy-in mode, and they're ignored like in Postgres
+		default:
+			return 0, sqlStateError{
+				Code:    "08P01",
+				Message: fmt.Sprintf(`unexpected message "%s" during COPY from stdin`, message.DefaultMessage().Name),
+			}
+		}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// copyRowReader reads the rows of COPY FROM. Each row contains a value for each of the fields.
+type copyRowReader interface {
+	// NextRow returns the next row. Returns io.EOF once there are no more rows.
+	NextRow() ([]tree.Datum, error)
+}
+
+// copyRecordReader reads the records of COPY FROM in either the text or the CSV format. Each record is returned as its
+// values, where a nil value is NULL.
+type copyRecordReader struct {
+	reader  *bufio.Reader
+	options copyOptions
+	fields  []*query.Field
+}
+
+var _ copyRowReader = (*copyRecordReader)(nil)
+
+// NextRow implements the interface copyRowReader.
+func (r *copyRecordReader) NextRow() ([]tree.Datum, error) {
+	values, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > len(r.fields) {
+		return nil, copyFormatError("extra data after last expected column")
+	} else if len(values) < len(r.fields) {
+		return nil, copyFormatError(`missing data for column "%s"`, r.fields[len(values)].Name)
+	}
+	row := make([]tree.Datum, len(values))
+	for i, value := range values {
+		row[i] = copyDatum(r.fields[i], value)
+	}
+	return row, nil
+}
+
+// readLine returns the next line without its line ending, along with the line ending. Returns io.EOF once there are
+// no more lines.
+func (r *copyRecordReader) readLine() (line string, lineEnding string, err error) {
+	line, err = r.reader.ReadString('\n')
+	if err == io.EOF {
+		if len(line) == 0 {
+			return "", "", io.EOF
+		}
+		// The last line does not need to end with a newline
+		err = nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return trimmed, line[len(trimmed):], nil
+}
+
+// Next returns the values of the next record. Returns io.EOF once there are no more records.
+func (r *copyRecordReader) Next() ([]*string, error) {
+	line, lineEnding, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == copyEndOfData {
+		return nil, io.EOF
+	}
+	if r.options.Format == tree.CopyFormatCSV {
+		return r.nextCSV(line, lineEnding)
+	}
+	return r.nextText(line)
+}
+
+// nextText returns the values of the given line in the text format. Values are separated by the delimiter, and
+// special characters are escaped with a backslash.
+func (r *copyRecordReader) nextText(line string) ([]*string, error) {
+	var values []*string
+	start := 0
+	for i := 0; i <= len(line); i++ {
+		if i < len(line) && line[i] == '\\' {
+			// The escaped character is skipped, as an escaped delimiter does not separate values
+			i++
+			continue
+		}
+		if i < len(line) && line[i] != r.options.Delimiter {
+			continue
+		}
+		// The null string is matched against the value before its escape sequences are replaced
+		rawValue := line[start:i]
+		start = i + 1
+		if rawValue == r.options.Null {
+			values = append(values, nil)
+			continue
+		}
+		value, err := unescapeCopyText(rawValue)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, &value)
+	}
+	return values, nil
+}
+
+// unescapeCopyText replaces the escape sequences of the given value in the text format.
+func unescapeCopyText(value string) (string, error) {
+	if strings.IndexByte(value, '\\') == -1 {
+		return value, nil
+	}
+	isOctal := func(c byte) bool {
+		return c >= '0' && c <= '7'
+	}
+	isHex := func(c byte) bool {
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			sb.WriteByte(value[i])
+			continue
+		}
+		i++
+		if i == len(value) {
+			return "", copyFormatError("unterminated escape sequence in COPY data")
+		}
+		switch c := value[i]; {
+		case c == 'b':
+			sb.WriteByte('\b')
+		case c == 'f':
+			sb.WriteByte('\f')
+		case c == 'n':
+			sb.WriteByte('\n')
+		case c == 'r':
+			sb.WriteByte('\r')
+		case c == 't':
+			sb.WriteByte('\t')
+		case c == 'v':
+			sb.WriteByte('\v')
+		case isOctal(c):
+			end := i + 1
+			for end < len(value) && end < i+3 && isOctal(value[end]) {
+				end++
+			}
+			octal, _ := strconv.ParseUint(value[i:end], 8, 8)
+			sb.WriteByte(byte(octal))
+			i = end - 1
+		case c == 'x' && i+1 < len(value) && isHex(value[i+1]):
+			end := i + 2
+			if end < len(value) && isHex(value[end]) {
+				end++
+			}
+			hex, _ := strconv.ParseUint(value[i+1:end], 16, 8)
+			sb.WriteByte(byte(hex))
+			i = end - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// nextCSV returns the values of the record starting with the given line in the CSV format. A quoted value may contain
+// line endings, in which case the record continues on the following lines.
+func (r *copyRecordReader) nextCSV(line string, lineEnding string) ([]*string, error) {
+	var values []*string
+	var value strings.Builder
+	quoted, inQuotes := false, false
+	endValue := func() {
+		// Only unquoted values match the null string, so that a quoted empty string is not NULL
+		valueString := value.String()
+		if !quoted && valueString == r.options.Null {
+			values = append(values, nil)
+		} else {
+			values = append(values, &valueString)
+		}
+		value.Reset()
+		quoted = false
+	}
+	for i := 0; ; i++ {
+		if i == len(line) {
+			if !inQuotes {
+				break
+			}
+			value.WriteString(lineEnding)
+			var err error
+			if line, lineEnding, err = r.readLine(); err == io.EOF {
+				return nil, copyFormatError("unterminated CSV quoted field")
+			} else if err != nil {
+				return nil, err
+			}
+			i = -1
+			continue
+		}
+		c := line[i]
+		if inQuotes {
+			if c == r.options.Escape && i+1 < len(line) && (line[i+1] == r.options.Quote || line[i+1] == r.options.Escape) {
+				value.WriteByte(line[i+1])
+				i++
+			} else if c == r.options.Quote {
+				inQuotes = false
+			} else {
+				value.WriteByte(c)
+			}
+			continue
+		}
+		switch c {
+		case r.options.Delimiter:
+			endValue()
+		case r.options.Quote:
+			quoted, inQuotes = true, true
+		default:
+			value.WriteByte(c)
+		}
+	}
+	endValue()
+	return values, nil
+}
+
+// copyBinaryReader reads the rows of COPY FROM in the binary format. The data begins with a header, which is followed by
+// the rows. Each row begins with its field count, and each field begins with its length, which is -1 for NULL. The
+// values use the binary format of their types. The data ends with a field count of -1.
+type copyBinaryReader struct {
+	reader *bufio.Reader
+	fields []*query.Field
+	done   bool
+}
+
+var _ copyRowReader = (*copyBinaryReader)(nil)
+
+// readHeader reads the header, which must precede the rows.
+func (r *copyBinaryReader) readHeader() error {
+	signature := make([]byte, len(copyBinarySignature))
+	if _, err := io.ReadFull(r.reader, signature); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return copyFormatError("COPY file signature not recognized")
+		}
+		return err
+	}
+	if string(signature) != copyBinarySignature {
+		return copyFormatError("COPY file signature not recognized")
+	}
+	header := make([]byte, 8)
+	if err := r.readValue(header); err != nil {
+		return err
+	}
+	flags := binary.BigEndian.Uint32(header)
+	if flags&(1<<16) != 0 {
+		return copyFormatError("invalid COPY file header (WITH OIDS)")
+	}
+	// The lower 16 bits are reserved for flags that may be ignored, while the upper bits are critical
+	if flags&^0xffff != 0 {
+		return copyFormatError("unrecognized critical flags in COPY file header")
+	}
+	// The header extension is skipped, as no extensions are defined
+	extensionLength := int32(binary.BigEndian.Uint32(header[4:]))
+	if extensionLength < 0 {
+		return copyFormatError("invalid COPY file header (missing length)")
+	}
+	if _, err := r.reader.Discard(int(extensionLength)); err != nil {
+		return copyFormatError("invalid COPY file header (wrong length)")
+	}
+	return nil
+}
+
+// NextRow implements the interface copyRowReader.
+func (r *copyBinaryReader) NextRow() ([]tree.Datum, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	fieldCount := make([]byte, 2)
+	if err := r.read(fieldCount); err != nil {
+		// The data may end without the trailer
+		return nil, err
+	}
+	count := int16(binary.BigEndian.Uint16(fieldCount))
+	if count == -1 {
+		r.done = true
+		return nil, io.EOF
+	}
+	if int(count) != len(r.fields) {
+		return nil, copyFormatError("row field count is %d, expected %d", count, len(r.fields))
+	}
+	row := make([]tree.Datum, len(r.fields))
+	for i, field := range r.fields {
+		length := make([]byte, 4)
+		if err := r.readValue(length); err != nil {
+			return nil, err
+		}
+		fieldLength := int32(binary.BigEndian.Uint32(length))
+		if fieldLength == -1 {
+			row[i] = tree.DNull
+			continue
+		} else if fieldLength < 0 {
+			return nil, copyFormatError("invalid field size")
+		}
+		data := make([]byte, fieldLength)
+		if err := r.readValue(data); err != nil {
+			return nil, err
+		}
+		objectID, err := messages.VitessFieldToDataTypeObjectID(field)
+		if err != nil {
+			return nil, err
+		}
+		if row[i], err = messages.DecodeBinary(oid.Oid(objectID), data); err != nil {
+			return nil, fmt.Errorf(`invalid binary data for column "%s": %w`, field.Name, err)
+		}
+	}
+	return row, nil
+}
+
+// read fills the given buffer. Returns io.EOF only when the data ended before any of the buffer was filled.
+func (r *copyBinaryReader) read(buffer []byte) error {
+	if _, err := io.ReadFull(r.reader, buffer); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return copyFormatError("unexpected EOF in COPY data")
+		}
+		return err
+	}
+	return nil
+}
+
+// readValue fills the given buffer, where the data must not end before the buffer has been filled.
+func (r *copyBinaryReader) readValue(buffer []byte) error {
+	if err := r.read(buffer); err != nil {
+		if err == io.EOF {
+			return copyFormatError("unexpected EOF in COPY data")
+		}
+		return err
+	}
+	return nil
+}
+
+// copyFrom handles the COPY FROM STDIN statement. The client sends the rows through CopyData messages once it has
+// received the CopyInResponse message, and the rows are inserted into the table in batches. Outside of a transaction
+// block, the rows are inserted within their own transaction, so that either all or none of the rows are inserted.
+func (l *Listener) copyFrom(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, queryString string, node *tree.CopyFrom) (err error) {
+	if _, err = transaction.Check(ConvertedQuery{String: queryString}); err != nil {
+		return err
+	}
+	if !node.Stdin {
+		return sqlStateError{Code: "0A000", Message: "COPY FROM is only supported with STDIN"}
+	}
+	options, err := newCopyOptions(node.Options)
+	if err != nil {
+		return err
+	}
+	columnsStatement, err := ast.CopyFromColumns(node)
+	if err != nil {
+		return err
+	}
+	var fields []*query.Field
+	if err = l.comQuery(mysqlConn, ConvertedQuery{String: queryString, AST: columnsStatement}, func(res *sqltypes.Result, more bool) error {
+		if fields == nil {
+			fields = res.Fields
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if transaction.Autocommit() {
+		if err = l.comQuery(mysqlConn, ConvertedQuery{String: "BEGIN", AST: &sqlparser.Begin{}}, func(*sqltypes.Result, bool) error {
+			return nil
+		}); err != nil {
+			return err
+		}
+		defer func() {
+			endTransaction := ConvertedQuery{String: "COMMIT", AST: &sqlparser.Commit{}}
+			if err != nil {
+				endTransaction = ConvertedQuery{String: "ROLLBACK", AST: &sqlparser.Rollback{}}
+			}
+			if endErr := l.comQuery(mysqlConn, endTransaction, func(*sqltypes.Result, bool) error {
+				return nil
+			}); endErr != nil && err == nil {
+				err = endErr
+			}
+		}()
+	}
+
+	formatCode := messages.FormatCode_Text
+	if options.Format == tree.CopyFormatBinary {
+		formatCode = messages.FormatCode_Binary
+	}
+	formatCodes := make([]int32, len(fields))
+	for i := range formatCodes {
+		formatCodes[i] = formatCode
+	}
+	if err = connection.Send(conn, messages.CopyInResponse{
+		IsTextual:   options.Format != tree.CopyFormatBinary,
+		FormatCodes: formatCodes,
+	}); err != nil {
+		return err
+	}
+
+	in := &copyInReader{conn: conn}
+	var rows copyRowReader
+	if options.Format == tree.CopyFormatBinary {
+		binaryReader := &copyBinaryReader{
+			reader: bufio.NewReader(in),
+			fields: fields,
+		}
+		if err = binaryReader.readHeader(); err != nil {
+			return err
+		}
+		rows = binaryReader
+	} else {
+		records := &copyRecordReader{
+			reader:  bufio.NewReader(in),
+			options: options,
+			fields:  fields,
+		}
+		if options.Header {
+			if _, err = records.Next(); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		rows = records
+	}
+	var rowCount int32
+	batch := make([][]tree.Datum, 0, copyBatchSize)
+	insertBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		insert, err := ast.CopyFromInsert(node, batch)
+		if err != nil {
+			return err
+		}
+		if err = l.comQuery(mysqlConn, ConvertedQuery{String: queryString, AST: insert}, func(*sqltypes.Result, bool) error {
+			return nil
+		}); err != nil {
+			return err
+		}
+		rowCount += int32(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var row []tree.Datum
+		if row, err = rows.NextRow(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		batch = append(batch, row)
+		if len(batch) == copyBatchSize {
+			if err = insertBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	// Any data following the end-of-data marker is ignored, but the client may still fail the copy until it's done
+	if _, err = io.Copy(io.Discard, in); err != nil {
+		return err
+	}
+	if err = insertBatch(); err != nil {
+		return err
+	}
+	return connection.Send(conn, messages.CommandComplete{
+		Query: queryString,
+		Rows:  rowCount,
+	})
+}
+
+// copyDatum returns the Datum of a COPY FROM value for the given column. The engine converts text values into the
+// column's type, except for booleans, as they're stored as integers.
+func copyDatum(field *query.Field, value *string) tree.Datum {
+	if value == nil {
+		return tree.DNull
+	}
+	if field.Type == sqltypes.Int8 {
+		if datum, err := tree.ParseDBool(*value); err == nil {
+			return datum
+		}
+	}
+	return tree.NewDString(*value)
+}
+
+// copyTo handles the COPY TO STDOUT statement. The rows are sent through CopyData messages as they're produced, with
+// each message containing a single row, rather than through DataRow messages.
+func (l *Listener) copyTo(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, queryString string, node *tree.CopyTo) error {
+	if _, err := transaction.Check(ConvertedQuery{String: queryString}); err != nil {
+		return err
+	}
+	if !node.Stdout {
+		return sqlStateError{Code: "0A000", Message: "COPY TO is only supported with STDOUT"}
+	}
+	options, err := newCopyOptions(node.Options)
+	if err != nil {
+		return err
+	}
+	selectStatement, err := ast.CopyToSelect(node)
+	if err != nil {
+		return err
+	}
+
+	var rowCount int32
+	started := false
+	if err = l.comQuery(mysqlConn, ConvertedQuery{String: queryString, AST: selectStatement}, func(res *sqltypes.Result, more bool) error {
+		// Results are returned in batches, but the copy only starts once
+		if !started {
+			started = true
+			formatCode := messages.FormatCode_Text
+			if options.Format == tree.CopyFormatBinary {
+				formatCode = messages.FormatCode_Binary
+			}
+			formatCodes := make([]int32, len(res.Fields))
+			for i := range formatCodes {
+				formatCodes[i] = formatCode
+			}
+			if err := connection.Send(conn, messages.CopyOutResponse{
+				IsTextual:   options.Format != tree.CopyFormatBinary,
+				FormatCodes: formatCodes,
+			}); err != nil {
+				return err
+			}
+			if header := options.encodeHeader(res.Fields); len(header) > 0 {
+				if err := connection.Send(conn, messages.CopyData{Data: header}); err != nil {
+					return err
+				}
+			}
+		}
+		for _, row := range res.Rows {
+			data, err := options.encodeRow(res.Fields, row)
+			if err != nil {
+				return err
+			}
+			if err = connection.Send(conn, messages.CopyData{Data: data}); err != nil {
+				return err
+			}
+		}
+		rowCount += int32(len(res.Rows))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if options.Format == tree.CopyFormatBinary {
+		// The trailer is a field count of -1
+		if err = connection.Send(conn, messages.CopyData{Data: []byte{0xff, 0xff}}); err != nil {
+			return err
+		}
+	}
+	if err = connection.Send(conn, messages.CopyDone{}); err != nil {
+		return err
+	}
+	return connection.Send(conn, messages.CommandComplete{
+		Query: queryString,
+		Rows:  rowCount,
+	})
+}
+
+// encodeHeader returns the data that precedes the rows of COPY TO. For the binary format, this is the signature
+// followed by the flags and the header extension length, which are both zero. For the other formats, this contains
+// the column names when the HEADER option was given.
+func (options copyOptions) encodeHeader(fields []*query.Field) []byte {
+	switch {
+	case options.Format == tree.CopyFormatBinary:
+		return append([]byte(copyBinarySignature), 0, 0, 0, 0, 0, 0, 0, 0)
+	case options.Header:
+		names := make([]sqltypes.Value, len(fields))
+		for i, field := range fields {
+			names[i] = sqltypes.NewVarChar(field.Name)
+		}
+		// The names are never NULL and are always text, so the row cannot fail to encode
+		data, _ := options.encodeRow(nil, names)
+		return data
+	default:
+		return nil
+	}
+}
+
+// encodeRow returns the given row in the format of the options. The fields describe each value, and values without a
+// field are encoded according to their own type.
+func (options copyOptions) encodeRow(fields []*query.Field, row []sqltypes.Value) ([]byte, error) {
+	field := func(i int) *query.Field {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return nil
+	}
+	var data []byte
+	if options.Format == tree.CopyFormatBinary {
+		data = binary.BigEndian.AppendUint16(data, uint16(len(row)))
+		for i, value := range row {
+			if value.IsNull() {
+				data = binary.BigEndian.AppendUint32(data, math.MaxUint32)
+				continue
+			}
+			encoded, err := messages.EncodeValue(field(i), value, messages.FormatCode_Binary)
+			if err != nil {
+				return nil, err
+			}
+			data = binary.BigEndian.AppendUint32(data, uint32(len(encoded)))
+			data = append(data, encoded...)
+		}
+		return data, nil
+	}
+
+	for i, value := range row {
+		if i > 0 {
+			data = append(data, options.Delimiter)
+		}
+		if value.IsNull() {
+			data = append(data, options.Null...)
+			continue
+		}
+		encoded, err := messages.EncodeValue(field(i), value, messages.FormatCode_Text)
+		if err != nil {
+			return nil, err
+		}
+		if options.Format == tree.CopyFormatCSV {
+			data = options.appendCSV(data, string(encoded))
+		} else {
+			data = options.appendText(data, string(encoded))
+		}
+	}
+	return append(data, '\n'), nil
+}
+
+// appendText appends the given value in the text format, where special characters are escaped with a backslash.
+func (options copyOptions) appendText(data []byte, value string) []byte {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\b':
+			data = append(data, '\\', 'b')
+		case '\f':
+			data = append(data, '\\', 'f')
+		case '\n':
+			data = append(data, '\\', 'n')
+		case '\r':
+			data = append(data, '\\', 'r')
+		case '\t':
+			data = append(data, '\\', 't')
+		case '\v':
+			data = append(data, '\\', 'v')
+		case '\\', options.Delimiter:
+			data = append(data, '\\', c)
+		default:
+			data = append(data, c)
+		}
+	}
+	return data
+}
+
+// appendCSV appends the given value in the CSV format. The value is quoted when it could otherwise be mistaken for
+// NULL, the end-of-data marker, or multiple values.
+func (options copyOptions) appendCSV(data []byte, value string) []byte {
+	needsQuotes := value == options.Null || value == copyEndOfData
+	for i := 0; i < len(value) && !needsQuotes; i++ {
+		switch value[i] {
+		case options.Delimiter, options.Quote, '\n', '\r':
+			needsQuotes = true
+		}
+	}
+	if !needsQuotes {
+		return append(data, value...)
+	}
+	data = append(data, options.Quote)
+	for i := 0; i < len(value); i++ {
+		if value[i] == options.Quote || value[i] == options.Escape {
+			data = append(data, options.Escape)
+		}
+		data = append(data, value[i])
+	}
+	return append(data, options.Quote)
+}
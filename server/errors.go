@@ -0,0 +1,285 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cockroacherrors "github.com/cockroachdb/errors"
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqle/dsess"
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/types"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/messages"
+	"github.com/dolthub/doltgresql/postgres/parser/pgcode"
+	"github.com/dolthub/doltgresql/postgres/parser/pgerror"
+)
+
+// sqlStateError is an error that is reported to the client with a specific SQLSTATE code. Other errors are translated
+// by errorSQLState, and are reported as internal errors when they're not recognized.
+type sqlStateError struct {
+	Code    string
+	Message string
+}
+
+var _ error = sqlStateError{}
+
+// Error implements the interface error.
+func (e sqlStateError) Error() string {
+	return e.Message
+}
+
+// tableError is an error from a statement that modifies the given table. The engine's constraint violations don't
+// always name their table, so it's taken from the statement instead.
+type tableError struct {
+	Table string
+	err   error
+}
+
+var _ error = tableError{}
+
+// Error implements the interface error.
+func (e tableError) Error() string {
+	return errorMessage(e.err)
+}
+
+// Unwrap returns the wrapped error.
+func (e tableError) Unwrap() error {
+	return e.err
+}
+
+// withStatementTable wraps the given constraint violation from the engine with the table that the statement modifies.
+// The error is returned as-is if it isn't a constraint violation, or if the statement doesn't modify a single table.
+func withStatementTable(statement sqlparser.Statement, err error) error {
+	if err == nil || !strings.HasPrefix(errorSQLState(err), "23") {
+		return err
+	}
+	var tableExprs sqlparser.TableExprs
+	switch statement := statement.(type) {
+	case *sqlparser.Insert:
+		return tableError{Table: statement.Table.Name.String(), err: err}
+	case *sqlparser.Update:
+		tableExprs = statement.TableExprs
+	case *sqlparser.Delete:
+		tableExprs = statement.TableExprs
+	}
+	if len(tableExprs) != 1 {
+		return err
+	}
+	if aliasedExpr, ok := tableExprs[0].(*sqlparser.AliasedTableExpr); ok {
+		if tableName, ok := aliasedExpr.Expr.(sqlparser.TableName); ok {
+			return tableError{Table: tableName.Name.String(), err: err}
+		}
+	}
+	return err
+}
+
+// mysqlErrorCodes are the SQLSTATE codes of the engine's errors, keyed by their MySQL error number. The engine returns
+// most of its errors with the same generic number, which are matched by engineErrorKinds instead.
+var mysqlErrorCodes = map[int]pgcode.Code{
+	mysql.ERDupEntry:                    pgcode.UniqueViolation,
+	mysql.ErNoReferencedRow2:            pgcode.ForeignKeyViolation,
+	mysql.ERRowIsReferenced2:            pgcode.ForeignKeyViolation,
+	mysql.ERBadNullError:                pgcode.NotNullViolation,
+	mysql.ERNoSuchTable:                 pgcode.UndefinedTable,
+	mysql.ERBadFieldError:               pgcode.UndefinedColumn,
+	mysql.ERKeyColumnDoesNotExist:       pgcode.UndefinedColumn,
+	mysql.ERBadDb:                       pgcode.InvalidCatalogName,
+	mysql.ERDbCreateExists:              pgcode.DuplicateDatabase,
+	mysql.ERTableExists:                 pgcode.DuplicateRelation,
+	mysql.ERDupFieldName:                pgcode.DuplicateColumn,
+	mysql.ERMultiplePriKey:              pgcode.InvalidTableDefinition,
+	mysql.ERSubqueryNo1Row:              pgcode.CardinalityViolation,
+	mysql.EROperandColumns:              pgcode.Syntax,
+	mysql.ERMixOfGroupFuncAndFields:     pgcode.Grouping,
+	mysql.ERTruncatedWrongValueForField: pgcode.InvalidTextRepresentation,
+	mysql.ERLockDeadlock:                pgcode.DeadlockDetected,
+	mysql.ERParseError:                  pgcode.Syntax,
+	mysql.ERSyntaxError:                 pgcode.Syntax,
+	1792:                                pgcode.ReadOnlySQLTransaction,    // ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+	3141:                                pgcode.InvalidTextRepresentation, // ER_INVALID_JSON_TEXT
+}
+
+// engineErrorKinds are the SQLSTATE codes of the engine's error kinds. The engine's errors lose their kind once they're
+// returned as MySQL errors, so they're recognized by their message instead.
+var engineErrorKinds = []struct {
+	Message string
+	Code    pgcode.Code
+}{
+	{sql.ErrTableNotFound.Message, pgcode.UndefinedTable},
+	{sql.ErrTableAlreadyExists.Message, pgcode.DuplicateRelation},
+	{sql.ErrColumnNotFound.Message, pgcode.UndefinedColumn},
+	{sql.ErrTableColumnNotFound.Message, pgcode.UndefinedColumn},
+	{sql.ErrUnknownIndexColumn.Message, pgcode.UndefinedColumn},
+	{sql.ErrColumnExists.Message, pgcode.DuplicateColumn},
+	{sql.ErrDuplicateColumn.Message, pgcode.DuplicateColumn},
+	{sql.ErrAmbiguousColumnName.Message, pgcode.AmbiguousColumn},
+	{sql.ErrDatabaseNotFound.Message, pgcode.InvalidCatalogName},
+	{sql.ErrDatabaseExists.Message, pgcode.DuplicateDatabase},
+	{sql.ErrFunctionNotFound.Message, pgcode.UndefinedFunction},
+	{sql.ErrUnknownSystemVariable.Message, pgcode.UndefinedObject},
+	{sql.ErrInvalidSystemVariableValue.Message, pgcode.InvalidParameterValue},
+	{sql.ErrCheckConstraintViolated.Message, pgcode.CheckViolation},
+	{sql.ErrValueOutOfRange.Message, pgcode.NumericValueOutOfRange},
+	{sql.ErrInvalidValue.Message, pgcode.InvalidTextRepresentation},
+	{sql.ErrLockDeadlock.Message, pgcode.DeadlockDetected},
+	{sql.ErrReadOnly.Message, pgcode.ReadOnlySQLTransaction},
+	{sql.ErrUnsupportedFeature.Message, pgcode.FeatureNotSupported},
+	{sql.ErrUnsupportedSyntax.Message, pgcode.FeatureNotSupported},
+	{types.ErrLengthBeyondLimit.Message, pgcode.StringDataRightTruncation},
+	{types.ErrConvertingToEnum.Message, pgcode.InvalidTextRepresentation},
+}
+
+// engineErrorPatterns are the compiled forms of engineErrorKinds, in the same order.
+var engineErrorPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(engineErrorKinds))
+	for i, kind := range engineErrorKinds {
+		patterns[i] = regexp.MustCompile(messagePattern(kind.Message))
+	}
+	return patterns
+}()
+
+// constraintErrorFields are the ErrorResponse fields that are taken from the messages of the engine's constraint
+// violations. Each field is the position of the message's argument that it's taken from, starting at 1, or zero if the
+// message doesn't contain it.
+var constraintErrorFields = []struct {
+	Message    string
+	Table      int
+	Column     int
+	Constraint int
+}{
+	{sql.ErrForeignKeyChildViolation.Message, 2, 0, 1},
+	{sql.ErrForeignKeyParentViolation.Message, 2, 0, 1},
+	{sql.ErrCheckConstraintViolated.Message, 0, 0, 1},
+	{sql.ErrDuplicateEntry.Message, 0, 0, 1},
+	{sql.ErrInsertIntoNonNullableProvidedNull.Message, 0, 1, 0},
+	{types.ErrLengthBeyondLimit.Message, 0, 2, 0},
+}
+
+// constraintErrorPatterns are the compiled forms of constraintErrorFields, in the same order.
+var constraintErrorPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(constraintErrorFields))
+	for i, fields := range constraintErrorFields {
+		patterns[i] = regexp.MustCompile("^" + messagePattern(fields.Message) + "$")
+	}
+	return patterns
+}()
+
+// messagePattern returns a regular expression that matches messages created from the given format string, with a
+// capturing group for each of the format's arguments.
+func messagePattern(format string) string {
+	verb := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+	return verb.ReplaceAllString(regexp.QuoteMeta(format), "(?s:(.*?))")
+}
+
+// errorSQLState returns the SQLSTATE code that should be reported for the given error.
+func errorSQLState(err error) string {
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		return stateErr.Code
+	}
+	// Dolt reports a transaction that conflicts with a concurrently committed one as a deadlock, but the transaction
+	// only needs to be retried
+	if strings.Contains(err.Error(), dsess.ErrRetryTransaction.Error()) {
+		return pgcode.SerializationFailure.String()
+	}
+	var mysqlErr *mysql.SQLError
+	if errors.As(err, &mysqlErr) {
+		if code, ok := mysqlErrorCodes[mysqlErr.Num]; ok {
+			return code.String()
+		}
+		for i, pattern := range engineErrorPatterns {
+			if pattern.MatchString(mysqlErr.Message) {
+				return engineErrorKinds[i].Code.String()
+			}
+		}
+	} else if code := pgerror.GetPGCode(err); code != pgcode.Uncategorized {
+		// Errors from the parser carry their own code
+		return code.String()
+	}
+	// Statements that can't be converted for the engine return errors along these lines
+	if strings.Contains(err.Error(), "not yet supported") {
+		return pgcode.FeatureNotSupported.String()
+	}
+	return pgcode.Internal.String()
+}
+
+// errorResponse returns the ErrorResponse that should be sent for the given error. Along with the message and SQLSTATE
+// code, this includes the position of syntax errors, the details and hints of errors that carry them, and the table,
+// column, and constraint of constraint violations.
+func errorResponse(err error) messages.ErrorResponse {
+	response := messages.ErrorResponse{
+		Severity:     messages.ErrorResponseSeverity_Error,
+		SqlStateCode: errorSQLState(err),
+		Message:      errorMessage(err),
+	}
+	response.Optional.Position = int32(pgerror.GetPosition(err))
+	if response.Optional.Position == 0 {
+		// The details of syntax errors only draw a caret under the query, which clients do themselves from the position
+		response.Optional.Detail = cockroacherrors.FlattenDetails(err)
+	}
+	response.Optional.Hint = cockroacherrors.FlattenHints(err)
+
+	var tableErr tableError
+	if errors.As(err, &tableErr) {
+		response.Optional.Table = tableErr.Table
+	}
+	var mysqlErr *mysql.SQLError
+	if !errors.As(err, &mysqlErr) {
+		return response
+	}
+	for i, pattern := range constraintErrorPatterns {
+		match := pattern.FindStringSubmatch(mysqlErr.Message)
+		if match == nil {
+			continue
+		}
+		argument := func(position int) string {
+			if position == 0 {
+				return ""
+			}
+			if unquoted, err := strconv.Unquote(match[position]); err == nil {
+				return unquoted
+			}
+			return match[position]
+		}
+		fields := constraintErrorFields[i]
+		if table := argument(fields.Table); len(table) > 0 {
+			response.Optional.Table = table
+		}
+		response.Optional.Column = argument(fields.Column)
+		response.Optional.Constraint = argument(fields.Constraint)
+		break
+	}
+	// The engine's primary keys don't have a name, so they're given the name that Postgres gives them by default
+	if strings.HasPrefix(mysqlErr.Message, sql.ErrPrimaryKeyViolation.Message) && len(response.Optional.Table) > 0 {
+		response.Optional.Constraint = response.Optional.Table + "_pkey"
+	}
+	return response
+}
+
+// errorMessage returns the message that should be reported for the given error. The engine's errors contain their
+// MySQL error number and SQLSTATE, which are left out, as they don't apply to Postgres.
+func errorMessage(err error) string {
+	var mysqlErr *mysql.SQLError
+	if errors.As(err, &mysqlErr) && mysqlErr == err {
+		return mysqlErr.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
+	"github.com/dolthub/go-mysql-server/sql"
+)
+
+// functions are the functions that Doltgres provides in addition to the engine's built-in functions.
+var functions = []sql.Function{
+	sql.Function2{Name: "pg_notify", Fn: NewPgNotify},
+}
+
+// registerFunctions adds the functions to the catalog of the running server's engine. Each server has its own engine,
+// so this is called once the server starts accepting connections.
+func registerFunctions() error {
+	runningServer, _ := sqlserver.GetRunningServer()
+	if runningServer == nil {
+		return fmt.Errorf("no server is running")
+	}
+	runningServer.Engine.Analyzer.Catalog.RegisterFunction(sql.NewEmptyContext(), functions...)
+	return nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// hbaConnectionType is the type of connection that a host-based authentication rule applies to.
+type hbaConnectionType string
+
+const (
+	hbaConnectionType_Local     hbaConnectionType = "local"
+	hbaConnectionType_Host      hbaConnectionType = "host"
+	hbaConnectionType_HostSSL   hbaConnectionType = "hostssl"
+	hbaConnectionType_HostNoSSL hbaConnectionType = "hostnossl"
+)
+
+// hbaMethod is the authentication method of a host-based authentication rule.
+type hbaMethod string
+
+const (
+	hbaMethod_Trust       hbaMethod = "trust"
+	hbaMethod_Reject      hbaMethod = "reject"
+	hbaMethod_Password    hbaMethod = "password"
+	hbaMethod_MD5         hbaMethod = "md5"
+	hbaMethod_ScramSHA256 hbaMethod = "scram-sha-256"
+	hbaMethod_Certificate hbaMethod = "cert"
+)
+
+// These are the keywords that may be used in place of a database, user, or address.
+const (
+	hbaKeyword_All         = "all"
+	hbaKeyword_SameUser    = "sameuser"
+	hbaKeyword_Replication = "replication"
+)
+
+// hbaRule is a single line of the HBA file.
+type hbaRule struct {
+	ConnectionType hbaConnectionType
+	Databases      []hbaToken
+	Users          []hbaToken
+	Address        *net.IPNet // Address is nil when the rule applies to all addresses, or when it is a local rule
+	Method         hbaMethod
+}
+
+// hbaToken is a single value within a column of the HBA file. Quoted values never match as keywords, so that a user
+// named "all" may be given.
+type hbaToken struct {
+	Value  string
+	Quoted bool
+}
+
+// loadHBAFile reads the host-based authentication rules from the file at the given path. The file uses the same
+// format as Postgres' pg_hba.conf, where each line contains the connection type, the databases, the users, the client
+// address (except for local connections), and the authentication method. Databases and users may be comma-separated
+// lists. Everything following a # is a comment.
+func loadHBAFile(path string) ([]hbaRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := make([]hbaRule, 0)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		columns, err := hbaColumns(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		rule, err := parseHBARule(columns)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// hbaColumns splits the given line into its whitespace-separated columns, with each column split into its
+// comma-separated tokens.
+func hbaColumns(line string) ([][]hbaToken, error) {
+	var columns [][]hbaToken
+	var column []hbaToken
+	var token strings.Builder
+	inQuotes, quoted, hasToken := false, false, false
+	endToken := func() {
+		if hasToken {
+			column = append(column, hbaToken{Value: token.String(), Quoted: quoted})
+		}
+		token.Reset()
+		quoted, hasToken = false, false
+	}
+	endColumn := func() {
+		endToken()
+		if len(column) > 0 {
+			columns = append(columns, column)
+		}
+		column = nil
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted, hasToken = true, true
+		case inQuotes:
+			token.WriteByte(c)
+		case c == '#':
+			i = len(line)
+		case c == ' ' || c == '\t' || c == '\r':
+			endColumn()
+		case c == ',':
+			if !hasToken {
+				return nil, fmt.Errorf("empty value in a comma-separated list")
+			}
+			endToken()
+			// The list continues after a comma, so the column is not ended by the following whitespace
+			for i+1 < len(line) && (line[i+1] == ' ' || line[i+1] == '\t') {
+				i++
+			}
+		default:
+			token.WriteByte(c)
+			hasToken = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated double quote")
+	}
+	endColumn()
+	return columns, nil
+}
+
+// parseHBARule parses the columns of a single line into a rule.
+func parseHBARule(columns [][]hbaToken) (rule hbaRule, err error) {
+	single := func(index int, name string) (string, error) {
+		if index >= len(columns) {
+			return "", fmt.Errorf("missing %s", name)
+		}
+		if len(columns[index]) != 1 {
+			return "", fmt.Errorf("multiple values specified for %s", name)
+		}
+		return columns[index][0].Value, nil
+	}
+
+	connectionType, err := single(0, "connection type")
+	if err != nil {
+		return rule, err
+	}
+	rule.ConnectionType = hbaConnectionType(connectionType)
+	switch rule.ConnectionType {
+	case hbaConnectionType_Local, hbaConnectionType_Host, hbaConnectionType_HostSSL, hbaConnectionType_HostNoSSL:
+	default:
+		return rule, fmt.Errorf("invalid connection type %q", connectionType)
+	}
+	if len(columns) < 2 {
+		return rule, fmt.Errorf("end-of-line before database specification")
+	}
+	rule.Databases = columns[1]
+	for _, database := range rule.Databases {
+		if !database.Quoted && (strings.HasPrefix(database.Value, "@") || database.Value == "samerole" || database.Value == "samegroup") {
+			return rule, fmt.Errorf("database %q is not supported", database.Value)
+		}
+	}
+	if len(columns) < 3 {
+		return rule, fmt.Errorf("end-of-line before role specification")
+	}
+	rule.Users = columns[2]
+	for _, user := range rule.Users {
+		if !user.Quoted && (strings.HasPrefix(user.Value, "@") || strings.HasPrefix(user.Value, "+")) {
+			return rule, fmt.Errorf("user %q is not supported, as there are no groups", user.Value)
+		}
+		if !user.Quoted && user.Value == hbaKeyword_SameUser {
+			return rule, fmt.Errorf("%q may only be used for databases", user.Value)
+		}
+	}
+
+	methodIndex := 3
+	if rule.ConnectionType != hbaConnectionType_Local {
+		address, err := single(3, "IP address")
+		if err != nil {
+			return rule, err
+		}
+		methodIndex = 4
+		if address != hbaKeyword_All {
+			if strings.Contains(address, "/") {
+				if _, rule.Address, err = net.ParseCIDR(address); err != nil {
+					return rule, fmt.Errorf("invalid IP address %q", address)
+				}
+			} else {
+				ip := net.ParseIP(address)
+				if ip == nil {
+					return rule, fmt.Errorf("invalid IP address %q, host names are not supported", address)
+				}
+				mask, err := single(4, "IP mask")
+				if err != nil {
+					return rule, err
+				}
+				maskIP := net.ParseIP(mask)
+				if maskIP == nil {
+					return rule, fmt.Errorf("invalid IP mask %q", mask)
+				}
+				if ipv4 := ip.To4(); ipv4 != nil {
+					ip, maskIP = ipv4, maskIP.To4()
+				}
+				if maskIP == nil || len(maskIP) != len(ip) {
+					return rule, fmt.Errorf("IP address and mask do not match")
+				}
+				ipMask := net.IPMask(maskIP)
+				rule.Address = &net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask}
+				methodIndex = 5
+			}
+		}
+	}
+
+	method, err := single(methodIndex, "authentication method")
+	if err != nil {
+		return rule, err
+	}
+	rule.Method = hbaMethod(method)
+	switch rule.Method {
+	case hbaMethod_Trust, hbaMethod_Reject, hbaMethod_Password, hbaMethod_MD5, hbaMethod_ScramSHA256:
+	case hbaMethod_Certificate:
+		if rule.ConnectionType != hbaConnectionType_HostSSL {
+			return rule, fmt.Errorf("cert authentication is only supported on hostssl connections")
+		}
+	default:
+		return rule, fmt.Errorf("invalid authentication method %q", method)
+	}
+	if len(columns) > methodIndex+1 {
+		return rule, fmt.Errorf("authentication option %q is not supported", columns[methodIndex+1][0].Value)
+	}
+	return rule, nil
+}
+
+// findHBARule returns the first of the given rules that matches the given connection. Returns false if no rules match.
+func findHBARule(rules []hbaRule, conn net.Conn, user string, database string) (hbaRule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(conn, user, database) {
+			return rule, true
+		}
+	}
+	return hbaRule{}, false
+}
+
+// Matches returns whether the rule applies to the given connection.
+func (rule hbaRule) Matches(conn net.Conn, user string, database string) bool {
+	_, isTLS := asTLSConn(conn)
+	isLocal := conn.RemoteAddr().Network() == "unix"
+	switch rule.ConnectionType {
+	case hbaConnectionType_Local:
+		if !isLocal {
+			return false
+		}
+	case hbaConnectionType_Host:
+		if isLocal {
+			return false
+		}
+	case hbaConnectionType_HostSSL:
+		if isLocal || !isTLS {
+			return false
+		}
+	case hbaConnectionType_HostNoSSL:
+		if isLocal || isTLS {
+			return false
+		}
+	}
+	if rule.Address != nil {
+		ip := net.ParseIP(hbaHost(conn))
+		if ip == nil || !rule.Address.Contains(ip) {
+			return false
+		}
+	}
+	return hbaTokensMatch(rule.Users, user, user) && hbaTokensMatch(rule.Databases, database, user)
+}
+
+// hbaTokensMatch returns whether any of the tokens match the given value. The user is used for the sameuser keyword,
+// which is only given for databases.
+func hbaTokensMatch(tokens []hbaToken, value string, user string) bool {
+	for _, token := range tokens {
+		if !token.Quoted {
+			switch token.Value {
+			case hbaKeyword_All:
+				return true
+			case hbaKeyword_SameUser:
+				if value == user {
+					return true
+				}
+				continue
+			case hbaKeyword_Replication:
+				// Replication connections are not supported, so they never match
+				continue
+			}
+		}
+		if token.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hbaHost returns the client's address, as it is displayed in authentication errors.
+func hbaHost(conn net.Conn) string {
+	if conn.RemoteAddr().Network() == "unix" {
+		return "[local]"
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// hbaError returns the error for a connection that was either rejected by a rule, or did not match any rule.
+func hbaError(conn net.Conn, user string, database string, rejected bool) error {
+	encryption := "no encryption"
+	if _, isTLS := asTLSConn(conn); isTLS {
+		encryption = "SSL encryption"
+	}
+	format := `no pg_hba.conf entry for host "%s", user "%s", database "%s", %s`
+	if rejected {
+		format = `pg_hba.conf rejects connection for host "%s", user "%s", database "%s", %s`
+	}
+	return sqlStateError{
+		Code:    "28000",
+		Message: fmt.Sprintf(format, hbaHost(conn), user, database, encryption),
+	}
+}
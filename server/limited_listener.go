@@ -16,6 +16,7 @@ package server
 
 import (
 	"crypto/tls"
+	"errors"
 	"net"
 	"sync"
 
@@ -40,41 +41,51 @@ var _ server.ProtocolListener = (*LimitedListener)(nil)
 
 // NewLimitedListener creates a new LimitedListener.
 func NewLimitedListener(listenerCfg mysql.ListenerConfig) (server.ProtocolListener, error) {
-	// Since this is intended for testing, we'll configure a test certificate so that we can test for SSL support
-	if len(certificate.Certificate) == 0 {
-		cert, key, err := testcerts.GenerateCerts()
-		if err != nil {
-			panic(err)
-		}
-		certificate, err = tls.X509KeyPair(cert, key)
-		if err != nil {
-			panic(err)
-		}
+	// Since this is intended for testing, we'll use a test certificate so that we can test for SSL support. The
+	// server's own certificate takes its place when one is given.
+	cert, key, err := testcerts.GenerateCerts()
+	if err != nil {
+		panic(err)
+	}
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		panic(err)
 	}
-	listener, err := NewListener(listenerCfg)
+	tlsConfig, err := newTLSConfig(certificate, "", defaultTLSMinVersion)
+	if err != nil {
+		panic(err)
+	}
+	listener, err := newListener(listenerCfg, tlsConfig)
 	if err != nil {
 		return nil, err
 	}
 	return &LimitedListener{
-		listener: listener.(*Listener),
+		listener: listener,
 		count:    0,
 	}, nil
 }
 
 // Accept implements the interface server.ProtocolListener.
 func (l *LimitedListener) Accept() {
+	if err := registerFunctions(); err != nil {
+		panic(err)
+	}
 	wg := sync.WaitGroup{}
-	wg.Add(2)
 	for {
 		if l.count >= 2 {
 			break
 		}
 		conn, err := l.listener.listener.Accept()
 		if err != nil {
+			// The listener may be closed before both connections have been made
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
 			panic(err)
 		}
 		l.count++
 
+		wg.Add(1)
 		go func() {
 			l.listener.HandleConnection(conn)
 			wg.Done()
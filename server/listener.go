@@ -15,15 +15,21 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/dolthub/dolt/go/libraries/doltcore/sqlserver"
 	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql"
 	"github.com/dolthub/go-mysql-server/sql/mysql_db"
 	"github.com/dolthub/vitess/go/mysql"
 	"github.com/dolthub/vitess/go/sqltypes"
@@ -32,37 +38,122 @@ import (
 	"github.com/dolthub/doltgresql/postgres/connection"
 	"github.com/dolthub/doltgresql/postgres/messages"
 	"github.com/dolthub/doltgresql/postgres/parser/parser"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
 	"github.com/dolthub/doltgresql/server/ast"
 )
 
-var (
-	connectionIDCounter uint32
-	processID           = int32(os.Getpid())
-	certificate         tls.Certificate //TODO: move this into the mysql.ListenerConfig
-)
+var connectionIDCounter uint32
 
 // Listener listens for connections to process PostgreSQL requests into Dolt requests.
 type Listener struct {
-	listener net.Listener
-	cfg      mysql.ListenerConfig
+	listener              net.Listener
+	cfg                   mysql.ListenerConfig
+	ssl                   sslConfig
+	passwordVerifiers     map[string]scramVerifier
+	hbaRules              []hbaRule
+	maxPreparedStatements int
+	shutdownTimeout       time.Duration
+	recordDirectory       string
+
+	connectionsMu sync.Mutex
+	connections   map[uint32]*activeConnection
+	draining      bool
+	closeOnce     sync.Once
+	drained       chan struct{}
 }
 
 var _ server.ProtocolListener = (*Listener)(nil)
 
+// listenerConfig is the configuration that a server gives to its listener.
+type listenerConfig struct {
+	// ssl is the SSL configuration of the listener's connections.
+	ssl sslConfig
+	// socketPath is the path of the Unix socket that the server listens on, or empty if it doesn't listen on one.
+	socketPath string
+	// socketPermissions are the access permissions that the Unix socket is given once it's created.
+	socketPermissions os.FileMode
+	// passwordVerifiers contains the password verifier of each user that may connect, keyed by the user's name. This is
+	// loaded from the auth file. When this is nil, authentication is disabled and every connection is trusted.
+	passwordVerifiers map[string]scramVerifier
+	// hbaRules are the host-based authentication rules, in the order that they were loaded from the HBA file. The first
+	// rule that matches a connection determines how the connection is authenticated. When this is nil, no HBA file was
+	// given, and every connection uses the same method (SCRAM-SHA-256 when an auth file was given, trust otherwise).
+	hbaRules []hbaRule
+	// maxPreparedStatements is the number of named prepared statements that a connection may have at once. Clients
+	// that cache their prepared statements are expected to close them, so a connection that reaches the limit is most
+	// likely leaking them. A limit of zero allows any number of prepared statements.
+	maxPreparedStatements int
+	// shutdownTimeout is how long the server waits for running queries to finish once it begins shutting down.
+	shutdownTimeout time.Duration
+	// recordDirectory is the directory that each connection's session is recorded to, or empty if sessions aren't
+	// recorded.
+	recordDirectory string
+}
+
+// listenerConfigs contains the configuration of each running server's listener, keyed by the server's port. The
+// engine's server creates the listener, so the listener looks up its configuration once it's created.
+var listenerConfigs sync.Map
+
+// newListenerConfig returns the default configuration of a listener.
+func newListenerConfig() *listenerConfig {
+	return &listenerConfig{
+		ssl:                   sslConfig{mode: sslMode_On},
+		socketPermissions:     defaultSocketPermissions,
+		maxPreparedStatements: defaultMaxPreparedStatements,
+		shutdownTimeout:       defaultShutdownTimeout,
+	}
+}
+
 // NewListener creates a new Listener.
 func NewListener(listenerCfg mysql.ListenerConfig) (server.ProtocolListener, error) {
+	return newListener(listenerCfg, nil)
+}
+
+// newListener creates a new Listener using the configuration of the server that it's listening for. The given TLS
+// configuration is used when the server does not have a certificate.
+func newListener(listenerCfg mysql.ListenerConfig, defaultTLSConfig *tls.Config) (*Listener, error) {
+	config := newListenerConfig()
+	if addr, ok := listenerCfg.Listener.Addr().(*net.TCPAddr); ok {
+		if serverConfig, ok := listenerConfigs.Load(addr.Port); ok {
+			config = serverConfig.(*listenerConfig)
+		}
+	}
+	// The listener is created after the Unix socket, so its permissions can be set now
+	if err := setSocketPermissions(config.socketPath, config.socketPermissions); err != nil {
+		return nil, err
+	}
+	ssl := config.ssl
+	if ssl.tlsConfig == nil {
+		ssl.tlsConfig = defaultTLSConfig
+	}
+	if ssl.mode == sslMode_Require && ssl.tlsConfig == nil {
+		return nil, fmt.Errorf("SSL is required, but the server does not have a certificate")
+	}
 	return &Listener{
-		listener: listenerCfg.Listener,
-		cfg:      listenerCfg,
+		listener:              listenerCfg.Listener,
+		cfg:                   listenerCfg,
+		ssl:                   ssl,
+		passwordVerifiers:     config.passwordVerifiers,
+		hbaRules:              config.hbaRules,
+		maxPreparedStatements: config.maxPreparedStatements,
+		shutdownTimeout:       config.shutdownTimeout,
+		recordDirectory:       config.recordDirectory,
+		connections:           make(map[uint32]*activeConnection),
+		drained:               make(chan struct{}),
 	}, nil
 }
 
 // Accept handles incoming connections.
 func (l *Listener) Accept() {
+	if err := registerFunctions(); err != nil {
+		fmt.Printf("Unable to register functions:\n%v\n", err)
+	}
 	for {
 		conn, err := l.listener.Accept()
 		if err != nil {
-			if err.Error() == "use of closed network connection" {
+			if errors.Is(err, net.ErrClosed) {
+				// The server is closed once every connection has been terminated
+				<-l.drained
 				break
 			}
 			fmt.Printf("Unable to accept connection:\n%v\n", err)
@@ -73,9 +164,15 @@ func (l *Listener) Accept() {
 	}
 }
 
-// Close stops the handling of incoming connections.
+// Close stops accepting connections, and then terminates the existing connections once their running queries finish.
+// Queries that are still running after the shutdown timeout are canceled. This returns once every connection has
+// closed.
 func (l *Listener) Close() {
-	_ = l.listener.Close()
+	l.closeOnce.Do(func() {
+		_ = l.listener.Close()
+		l.drain()
+		close(l.drained)
+	})
 }
 
 // Addr returns the address that the listener is listening on.
@@ -91,6 +188,11 @@ func (l *Listener) HandleConnection(conn net.Conn) {
 	}
 	mysqlConn.ConnectionID = atomic.AddUint32(&connectionIDCounter, 1)
 
+	recorder := startRecording(l.recordDirectory, mysqlConn.ConnectionID)
+	if recorder != nil {
+		conn = recorder.Wrap(conn)
+	}
+
 	var err error
 	var returnErr error
 	defer func() {
@@ -100,10 +202,16 @@ func (l *Listener) HandleConnection(conn net.Conn) {
 		if returnErr != nil {
 			fmt.Println(returnErr.Error())
 		}
+		removeBackendKey(int32(mysqlConn.ConnectionID))
 		l.cfg.Handler.ConnectionClosed(mysqlConn)
 		if err := conn.Close(); err != nil {
 			fmt.Printf("Failed to properly close connection:\n%v\n", err)
 		}
+		if recorder != nil {
+			if err := recorder.Close(); err != nil {
+				fmt.Printf("Failed to properly record the session:\n%v\n", err)
+			}
+		}
 	}()
 	l.cfg.Handler.NewConnection(mysqlConn)
 
@@ -111,41 +219,54 @@ func (l *Listener) HandleConnection(conn net.Conn) {
 	// The initial message may be one of a few different messages, so we'll check for those.
 InitialMessageLoop:
 	for {
-		initialMessages, err := connection.ReceiveIntoAny(conn,
+		initialMessage, ok, err := connection.ReceiveIntoAny(conn,
 			messages.StartupMessage{},
 			messages.SSLRequest{},
-			messages.GSSENCRequest{})
+			messages.GSSENCRequest{},
+			messages.CancelRequest{})
 		if err != nil {
 			if err != io.EOF {
 				returnErr = err
 			}
 			return
 		}
-		if len(initialMessages) != 1 {
-			returnErr = fmt.Errorf("Expected a single message upon starting connection, terminating connection")
+		if !ok {
+			returnErr = fmt.Errorf("Unrecognized message upon starting connection, terminating connection")
 			return
 		}
-		initialMessage := initialMessages[0]
 
 		switch initialMessage := initialMessage.(type) {
 		case messages.StartupMessage:
+			if l.ssl.requiresSSL(conn) {
+				returnErr = fmt.Errorf("rejected a connection from %s that did not use SSL", conn.RemoteAddr().String())
+				_ = connection.Send(conn, messages.ErrorResponse{
+					Severity:     messages.ErrorResponseSeverity_Fatal,
+					SqlStateCode: "28000",
+					Message:      "SSL connection is required",
+					Optional: messages.ErrorResponseOptionalFields{
+						Routine: "ProcessStartupPacket",
+					},
+				})
+				return
+			}
 			startupMessage = initialMessage
 			break InitialMessageLoop
 		case messages.SSLRequest:
-			hasCertificate := len(certificate.Certificate) > 0
+			supportsSSL := l.ssl.supportsSSL()
 			if err := connection.Send(conn, messages.SSLResponse{
-				SupportsSSL: hasCertificate,
+				SupportsSSL: supportsSSL,
 			}); err != nil {
 				returnErr = err
 				return
 			}
 			// If we have a certificate and the client has asked for SSL support, then we switch here.
 			// We can't start in SSL mode, as the client does not attempt the handshake until after our response.
-			if hasCertificate {
-				conn = tls.Server(conn, &tls.Config{
-					Certificates: []tls.Certificate{certificate},
-				})
+			if supportsSSL {
+				conn = tls.Server(connection.Unwrap(conn), l.ssl.tlsConfig)
 				mysqlConn.Conn = conn
+				if recorder != nil {
+					conn = recorder.Wrap(conn)
+				}
 			}
 		case messages.GSSENCRequest:
 			if err = connection.Send(conn, messages.GSSENCResponse{
@@ -154,12 +275,24 @@ InitialMessageLoop:
 				returnErr = err
 				return
 			}
+		case messages.CancelRequest:
+			// A cancel request is sent on its own connection, which is closed once the request has been handled
+			returnErr = l.cancelQuery(mysqlConn, initialMessage)
+			return
 		default:
 			returnErr = fmt.Errorf("Unexpected initial message, terminating connection")
 			return
 		}
 	}
 
+	ac, err := l.addConnection(conn, mysqlConn)
+	if err != nil {
+		returnErr = err
+		sendFatal(conn, err)
+		return
+	}
+	defer l.removeConnection(ac)
+
 	err = l.sendClientStartupMessages(conn, startupMessage, mysqlConn)
 	if err != nil {
 		returnErr = err
@@ -196,106 +329,176 @@ InitialMessageLoop:
 		return
 	}
 
-	preparedStatements := make(map[string]ConvertedQuery)
+	notifications := newNotificationSession(mysqlConn.ConnectionID, conn)
+	defer notifications.Close()
+	transaction := newTransactionState(notifications)
+	preparedStatements := make(map[string]PreparedStatementData)
+	portals := make(map[string]*PortalData)
+	defer closePortals(portals)
+	// When an error occurs while processing the extended query protocol, the error has already been sent, so the
+	// client's remaining messages are discarded until the next Sync, which is then processed as usual.
+	skipUntilSync := false
+	// The idle timeouts only apply while the client is expected to send its next query, which is after a ReadyForQuery
+	readyForQuery := true
 	for {
-		receivedMessages, err := connection.Receive(conn)
+		message, err := l.receive(ac, readyForQuery, transaction.Indicator())
+		notifications.Busy()
 		if err != nil {
-			returnErr = err
-			return
-		} else if len(receivedMessages) == 0 {
-			returnErr = fmt.Errorf("data received but contained no messages, terminating connection")
+			var stateErr sqlStateError
+			if errors.As(err, &stateErr) {
+				sendFatal(conn, err)
+			} else if err != io.EOF {
+				returnErr = err
+			}
 			return
 		}
+		readyForQuery = false
+		if skipUntilSync {
+			switch message.(type) {
+			case messages.Terminate, messages.Sync:
+				skipUntilSync = false
+			default:
+				continue
+			}
+		}
 
-		portals := make(map[string]ConvertedQuery)
-	ReadMessages:
-		for _, message := range receivedMessages {
-			switch message := message.(type) {
-			case messages.Terminate:
-				return
-			case messages.Execute:
-				// TODO: implement the RowMax
-				if err = l.execute(conn, mysqlConn, portals[message.Portal]); err != nil {
-					l.endOfMessages(conn, err)
-					break ReadMessages
-				}
-			case messages.Query:
-				var ok bool
-				if ok, err = l.handledPSQLCommands(conn, mysqlConn, message.String); !ok && err == nil {
-					var query ConvertedQuery
-					if query, err = l.convertQuery(message.String); err != nil {
-						l.endOfMessages(conn, err)
-						break ReadMessages
-					} else {
-						// The Deallocate message must not get passed to the engine, since we handle allocation / deallocation of
-						// prepared statements at this layer
-						switch stmt := query.AST.(type) {
-						case *sqlparser.Deallocate:
-							_, ok := preparedStatements[stmt.Name]
-							if !ok {
-								err = fmt.Errorf("prepared statement %s does not exist", stmt.Name)
-								break ReadMessages
-							}
-							delete(preparedStatements, stmt.Name)
-
-							commandComplete := messages.CommandComplete{
-								Query: query.String,
-								Rows:  0,
-							}
-
-							if err = connection.Send(conn, commandComplete); err != nil {
-								returnErr = err
-								return
-							}
-						default:
-							err = l.execute(conn, mysqlConn, query)
-						}
-					}
-				}
-				l.endOfMessages(conn, err)
-			case messages.Parse:
-				// TODO: fully support prepared statements
-				var query ConvertedQuery
-				if query, err = l.convertQuery(message.Query); err != nil {
-					l.endOfMessages(conn, err)
-					break ReadMessages
+		switch message := message.(type) {
+		case messages.Terminate:
+			return
+		case messages.Execute:
+			portal, ok := portals[message.Portal]
+			if !ok {
+				err = portalNotFoundError(message.Portal)
+			} else {
+				drainPortals(portals, portal)
+				err = l.executePortal(conn, mysqlConn, transaction, portal, message.RowMax)
+			}
+			if err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+			}
+		case messages.Query:
+			// A Query message destroys the unnamed prepared statement and portal
+			delete(preparedStatements, "")
+			closePortal(portals, "")
+			drainPortals(portals, nil)
+			var ok bool
+			if ok, err = l.handledPSQLCommands(conn, mysqlConn, transaction, message.String); !ok && err == nil {
+				err = l.simpleQuery(conn, mysqlConn, transaction, preparedStatements, message.String)
+			}
+			l.endOfMessages(conn, transaction, err)
+			readyForQuery = true
+			// Portals only last until the end of their transaction, which is the end of the query outside of a
+			// transaction block
+			if transaction.Indicator() == messages.ReadyForQueryTransactionIndicator_Idle {
+				closePortals(portals)
+			}
+		case messages.Parse:
+			var preparedStatement PreparedStatementData
+			if preparedStatement, err = l.prepare(message); err == nil {
+				_, err = transaction.Check(preparedStatement.Query)
+			}
+			if err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+				continue
+			}
+			if err = addPreparedStatement(preparedStatements, message.Name, preparedStatement, l.maxPreparedStatements); err == nil {
+				err = connection.Send(conn, messages.ParseComplete{})
+			}
+			if err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+			}
+		case messages.Describe:
+			var query ConvertedQuery
+			var statement parser.Statement
+			var resultFormatCodes []int32
+			if message.IsPrepared {
+				preparedStatement, ok := preparedStatements[message.Target]
+				if !ok {
+					err = preparedStatementNotFoundError(message.Target)
 				} else {
-					preparedStatements[message.Name] = query
-				}
-				if err = connection.Send(conn, messages.ParseComplete{}); err != nil {
-					l.endOfMessages(conn, err)
-					break ReadMessages
+					err = connection.Send(conn, messages.ParameterDescription{
+						ObjectIDs: parameterObjectIDs(preparedStatement.ParameterTypes),
+					})
+					query = preparedStatement.Query
+					statement = preparedStatement.Statement
 				}
-			case messages.Describe:
-				var query ConvertedQuery
-				if message.IsPrepared {
-					query = preparedStatements[message.Target]
+			} else {
+				portal, ok := portals[message.Target]
+				if !ok {
+					err = portalNotFoundError(message.Target)
 				} else {
-					query = portals[message.Target]
+					query = portal.Query
+					statement = portal.Statement
+					resultFormatCodes = portal.ResultFormatCodes
 				}
-				if err = l.describe(conn, mysqlConn, message, query); err != nil {
-					l.endOfMessages(conn, err)
-					break ReadMessages
+			}
+			if err == nil {
+				drainPortals(portals, nil)
+				if isNotificationStatement(statement.AST) {
+					// Notification statements never return rows
+					err = connection.Send(conn, messages.NoData{})
+				} else {
+					err = l.describe(conn, mysqlConn, query, resultFormatCodes)
 				}
-			case messages.Sync:
-				l.endOfMessages(conn, nil)
-			case messages.Bind:
-				// TODO: fully support prepared statements
-				portals[message.DestinationPortal] = preparedStatements[message.SourcePreparedStatement]
-				if err = connection.Send(conn, messages.BindComplete{}); err != nil {
-					l.endOfMessages(conn, err)
-					break ReadMessages
+			}
+			if err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+			}
+		case messages.Close:
+			// Closing a statement or portal that doesn't exist is not an error
+			if message.ClosingPreparedStatement {
+				delete(preparedStatements, message.Target)
+			} else {
+				closePortal(portals, message.Target)
+			}
+			if err = connection.Send(conn, messages.CloseComplete{}); err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+			}
+		case messages.CopyData, messages.CopyDone, messages.CopyFail:
+			// These remain when COPY FROM fails before the client has finished sending its data, and they're ignored
+			// like in Postgres
+		case messages.Flush:
+			// Messages are sent as soon as they're created, so there's never any pending output to flush
+		case messages.Sync:
+			l.endOfMessages(conn, transaction, nil)
+			readyForQuery = true
+			if transaction.Indicator() == messages.ReadyForQueryTransactionIndicator_Idle {
+				closePortals(portals)
+			}
+		case messages.Bind:
+			var query ConvertedQuery
+			preparedStatement, ok := preparedStatements[message.SourcePreparedStatement]
+			if !ok {
+				err = preparedStatementNotFoundError(message.SourcePreparedStatement)
+			} else if _, err = transaction.Check(preparedStatement.Query); err == nil {
+				query, err = bindParameters(preparedStatement, message)
+			}
+			if err == nil {
+				closePortal(portals, message.DestinationPortal)
+				portals[message.DestinationPortal] = &PortalData{
+					Query:             query,
+					Statement:         preparedStatement.Statement,
+					ResultFormatCodes: message.ResultFormatCodes,
 				}
-			default:
-				l.endOfMessages(conn, fmt.Errorf(`Unexpected message "%s"`, message.DefaultMessage().Name))
-				break ReadMessages
+				err = connection.Send(conn, messages.BindComplete{})
 			}
+			if err != nil {
+				l.pipelineError(conn, transaction, err)
+				skipUntilSync = true
+			}
+		default:
+			l.pipelineError(conn, transaction, fmt.Errorf(`Unexpected message "%s"`, message.DefaultMessage().Name))
+			skipUntilSync = true
 		}
 	}
 }
 
-// sendClientStartupMessages sends introductory messages to the client and returns any error
-// TODO: implement users and authentication
+// sendClientStartupMessages authenticates the client, sends introductory messages to the client, and returns any error
 func (l *Listener) sendClientStartupMessages(conn net.Conn, startupMessage messages.StartupMessage, mysqlConn *mysql.Conn) error {
 	if user, ok := startupMessage.Parameters["user"]; ok && len(user) > 0 {
 		var host string
@@ -320,27 +523,37 @@ func (l *Listener) sendClientStartupMessages(conn net.Conn, startupMessage messa
 		}
 	}
 
-	if err := connection.Send(conn, messages.AuthenticationOk{}); err != nil {
+	// Like Postgres, the database defaults to the user's name
+	database := startupMessage.Parameters["database"]
+	if len(database) == 0 {
+		database = mysqlConn.User
+	}
+	if err := l.authenticate(conn, mysqlConn.User, database); err != nil {
 		return err
 	}
 
-	if err := connection.Send(conn, messages.ParameterStatus{
-		Name:  "server_version",
-		Value: "15.0",
-	}); err != nil {
+	if err := l.startupParameters(mysqlConn, startupMessage); err != nil {
+		// Like Postgres, an invalid value for a parameter ends the connection
+		response := errorResponse(err)
+		response.Severity = messages.ErrorResponseSeverity_Fatal
+		_ = connection.Send(conn, response)
 		return err
 	}
-
-	if err := connection.Send(conn, messages.ParameterStatus{
-		Name:  "client_encoding",
-		Value: "UTF8",
-	}); err != nil {
+	if err := l.sendParameterStatuses(conn, mysqlConn, reportedParameters); err != nil {
+		return err
+	}
+	if err := l.readIdleTimeouts(mysqlConn); err != nil {
 		return err
 	}
 
+	processID := int32(mysqlConn.ConnectionID)
+	secretKey, err := newBackendKey(processID)
+	if err != nil {
+		return err
+	}
 	if err := connection.Send(conn, messages.BackendKeyData{
 		ProcessID: processID,
-		SecretKey: 0,
+		SecretKey: secretKey,
 	}); err != nil {
 		return err
 	}
@@ -348,23 +561,238 @@ func (l *Listener) sendClientStartupMessages(conn net.Conn, startupMessage messa
 	return nil
 }
 
+// simpleQuery handles the given query from a Query message. The query's statements are run in order, stopping at the
+// first statement that returns an error. Like Postgres, a query with multiple statements runs within an implicit
+// transaction when it's not within a transaction block, so that its statements are committed or rolled back together.
+func (l *Listener) simpleQuery(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, preparedStatements map[string]PreparedStatementData, queryString string) (err error) {
+	statements, err := parser.Parse(queryString)
+	if err != nil {
+		return err
+	}
+	if len(statements) == 0 {
+		return connection.Send(conn, messages.EmptyQueryResponse{})
+	}
+	if len(statements) > 1 {
+		defer func() {
+			err = l.endImplicitTransaction(mysqlConn, transaction, err)
+		}()
+	}
+	for _, s := range statements {
+		// A COMMIT or ROLLBACK ends the implicit transaction, so the remaining statements run within a new one
+		if len(statements) > 1 && transaction.Autocommit() {
+			if err = l.beginImplicitTransaction(mysqlConn, transaction); err != nil {
+				return err
+			}
+		}
+		if err = l.simpleStatement(conn, mysqlConn, transaction, preparedStatements, s.SQL, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// beginImplicitTransaction begins the implicit transaction that the statements of a query run within.
+func (l *Listener) beginImplicitTransaction(mysqlConn *mysql.Conn, transaction *transactionState) error {
+	if err := l.comQuery(mysqlConn, ConvertedQuery{String: "BEGIN", AST: &sqlparser.Begin{}}, func(*sqltypes.Result, bool) error {
+		return nil
+	}); err != nil {
+		return err
+	}
+	transaction.BeginImplicit()
+	return nil
+}
+
+// endImplicitTransaction ends the implicit transaction, if there is one. The transaction is committed when the given
+// error is nil, and rolled back otherwise. Returns the given error, or the error from committing the transaction.
+func (l *Listener) endImplicitTransaction(mysqlConn *mysql.Conn, transaction *transactionState, err error) error {
+	if !transaction.Implicit() {
+		return err
+	}
+	endTransaction := ConvertedQuery{String: "COMMIT", AST: &sqlparser.Commit{}}
+	if err != nil {
+		endTransaction = ConvertedQuery{String: "ROLLBACK", AST: &sqlparser.Rollback{}}
+	}
+	endErr := l.comQuery(mysqlConn, endTransaction, func(*sqltypes.Result, bool) error {
+		return nil
+	})
+	transaction.Executed(endTransaction)
+	if err != nil {
+		return err
+	}
+	return endErr
+}
+
+// simpleStatement handles a single statement from a Query message. The query string is the statement's portion of the
+// message's query.
+func (l *Listener) simpleStatement(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, preparedStatements map[string]PreparedStatementData, queryString string, s parser.Statement) error {
+	// COPY receives or sends its rows through the connection, and notifications are tracked by the connection, so
+	// they're handled here rather than by the engine
+	switch stmt := s.AST.(type) {
+	case *tree.CopyFrom:
+		return l.copyFrom(conn, mysqlConn, transaction, queryString, stmt)
+	case *tree.CopyTo:
+		return l.copyTo(conn, mysqlConn, transaction, queryString, stmt)
+	case *tree.Listen, *tree.Unlisten, *tree.Notify:
+		return l.notificationStatement(conn, transaction, queryString, stmt)
+	}
+	query, err := l.convertStatement(queryString, s)
+	if err != nil {
+		return err
+	}
+	// The engine is already within the implicit transaction, which BEGIN turns into a transaction block
+	if _, ok := query.AST.(*sqlparser.Begin); ok && transaction.Implicit() {
+		transaction.Executed(query)
+		return connection.Send(conn, messages.CommandComplete{
+			Query: query.String,
+			Rows:  0,
+		})
+	}
+	// The Deallocate message must not get passed to the engine, since we handle allocation / deallocation of prepared
+	// statements at this layer
+	if stmt, ok := query.AST.(*sqlparser.Deallocate); ok {
+		if _, err = transaction.Check(query); err != nil {
+			return err
+		}
+		if err = deallocatePreparedStatements(preparedStatements, stmt.Name); err != nil {
+			return err
+		}
+		return connection.Send(conn, messages.CommandComplete{
+			Query: query.String,
+			Rows:  0,
+		})
+	}
+	return l.execute(conn, mysqlConn, transaction, query)
+}
+
 // execute handles running the given query. This will post the RowDescription, DataRow, and CommandComplete messages.
-func (l *Listener) execute(conn net.Conn, mysqlConn *mysql.Conn, query ConvertedQuery) error {
+func (l *Listener) execute(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, query ConvertedQuery) error {
+	query, err := transaction.Check(query)
+	if err != nil {
+		return err
+	}
+	if err = l.executeQuery(conn, mysqlConn, query, nil, true); err != nil {
+		return err
+	}
+	transaction.Executed(query)
+	return nil
+}
+
+// executePortal handles running the given portal. This will post the DataRow messages, followed by either the
+// CommandComplete message, or the PortalSuspended message when the portal has more rows than the given row maximum. A
+// suspended portal continues from where it left off when it is executed again, while an exhausted portal returns no
+// rows. The RowDescription message is not posted, as the client learns of the rows' description through the Describe
+// message.
+func (l *Listener) executePortal(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, portal *PortalData, rowMax int32) error {
+	query, err := transaction.Check(portal.Query)
+	if err != nil {
+		return err
+	}
+	portal.Query = query
+	if portal.exhausted {
+		return connection.Send(conn, messages.CommandComplete{
+			Query: portal.Query.String,
+			Rows:  0,
+		})
+	}
+	if isNotificationStatement(portal.Statement.AST) {
+		portal.exhausted = true
+		return l.notificationStatement(conn, transaction, portal.Query.String, portal.Statement.AST)
+	}
+	if portal.rows == nil {
+		// Without a row maximum, all of the rows are sent as they're produced, so there's no need to keep the query around
+		if rowMax <= 0 {
+			portal.exhausted = true
+			if err = l.executeQuery(conn, mysqlConn, portal.Query, portal.ResultFormatCodes, false); err != nil {
+				return err
+			}
+			transaction.Executed(portal.Query)
+			return nil
+		}
+		portal.rows = l.newRowIterator(mysqlConn, portal.Query)
+	}
+
+	rows, finished, err := portal.rows.Next(rowMax)
+	if err != nil {
+		portal.Close()
+		return err
+	}
+	formatCodes, err := columnFormatCodes(portal.ResultFormatCodes, len(portal.rows.Fields()))
+	if err != nil {
+		portal.Close()
+		return err
+	}
+	for _, row := range rows {
+		if err = connection.Send(conn, messages.DataRow{
+			Values:      row,
+			Fields:      portal.rows.Fields(),
+			FormatCodes: formatCodes,
+		}); err != nil {
+			return err
+		}
+	}
+	if !finished {
+		return connection.Send(conn, messages.PortalSuspended{})
+	}
+
+	commandComplete := messages.CommandComplete{
+		Query: portal.Query.String,
+		Rows:  int32(len(rows)),
+	}
+	if commandComplete.IsIUD() {
+		commandComplete.Rows = int32(portal.rows.RowsAffected())
+	}
+	portal.Close()
+	transaction.Executed(portal.Query)
+	if err = l.sendWarnings(conn, mysqlConn); err != nil {
+		return err
+	}
+	if err = l.sendChangedParameterStatuses(conn, mysqlConn, portal.Query); err != nil {
+		return err
+	}
+	if err = l.updateIdleTimeouts(mysqlConn, portal.Query); err != nil {
+		return err
+	}
+	return connection.Send(conn, commandComplete)
+}
+
+// executeQuery runs the given query, encoding the rows using the given result format codes. The RowDescription message
+// is only posted when sendRowDescription is true.
+func (l *Listener) executeQuery(conn net.Conn, mysqlConn *mysql.Conn, query ConvertedQuery, resultFormatCodes []int32, sendRowDescription bool) error {
 	commandComplete := messages.CommandComplete{
 		Query: query.String,
 		Rows:  0,
 	}
 
+	var rowDescription messages.RowDescription
+	described := false
 	if err := l.comQuery(mysqlConn, query, func(res *sqltypes.Result, more bool) error {
-		if err := connection.Send(conn, messages.RowDescription{
-			Fields: res.Fields,
-		}); err != nil {
-			return err
+		// Results are returned in batches, but the row description should only be sent once
+		if !described {
+			described = true
+			formatCodes, err := columnFormatCodes(resultFormatCodes, len(res.Fields))
+			if err != nil {
+				return err
+			}
+			rowDescription = messages.RowDescription{
+				Fields:      res.Fields,
+				FormatCodes: formatCodes,
+			}
+			if sendRowDescription && len(res.Fields) > 0 {
+				if err = connection.Send(conn, rowDescription); err != nil {
+					return err
+				}
+			}
 		}
 
 		for _, row := range res.Rows {
+			// Statements such as SET return an empty row, which isn't sent as there are no columns to describe it
+			if len(row) == 0 && len(res.Fields) == 0 {
+				continue
+			}
 			if err := connection.Send(conn, messages.DataRow{
-				Values: row,
+				Values:      row,
+				Fields:      rowDescription.Fields,
+				FormatCodes: rowDescription.FormatCodes,
 			}); err != nil {
 				return err
 			}
@@ -383,6 +811,15 @@ func (l *Listener) execute(conn net.Conn, mysqlConn *mysql.Conn, query Converted
 		return err
 	}
 
+	if err := l.sendWarnings(conn, mysqlConn); err != nil {
+		return err
+	}
+	if err := l.sendChangedParameterStatuses(conn, mysqlConn, query); err != nil {
+		return err
+	}
+	if err := l.updateIdleTimeouts(mysqlConn, query); err != nil {
+		return err
+	}
 	if err := connection.Send(conn, commandComplete); err != nil {
 		return err
 	}
@@ -390,63 +827,52 @@ func (l *Listener) execute(conn net.Conn, mysqlConn *mysql.Conn, query Converted
 	return nil
 }
 
-// describe handles the description of the given query. This will post the ParameterDescription and RowDescription messages.
-func (l *Listener) describe(conn net.Conn, mysqlConn *mysql.Conn, message messages.Describe, statement ConvertedQuery) error {
-	//TODO: fully support prepared statements
-	if err := connection.Send(conn, messages.ParameterDescription{
-		ObjectIDs: nil,
-	}); err != nil {
+// describe handles the description of the given query. This will post the RowDescription message, or the NoData
+// message if the query does not return any rows. The result format codes are only known once a statement has been
+// bound, so they should be nil when describing a prepared statement. The query is only analyzed, so describing a query
+// never has any side effects.
+func (l *Listener) describe(conn net.Conn, mysqlConn *mysql.Conn, statement ConvertedQuery, resultFormatCodes []int32) error {
+	// The handler analyzes the MySQL form of the statement, where any unbound parameters are bind variables
+	query := statement.String
+	if statement.AST != nil {
+		query = sqlparser.String(statement.AST)
+	}
+	fields, err := l.cfg.Handler.ComPrepare(mysqlConn, query, &mysql.PrepareData{
+		PrepareStmt: query,
+	})
+	if err != nil {
 		return err
 	}
-
-	//TODO: properly handle these statements
-	if ImplicitlyCommits(statement.String) {
-		return fmt.Errorf("We do not yet support the Describe message for the given statement")
+	if len(fields) == 0 {
+		return connection.Send(conn, messages.NoData{})
 	}
-	// We'll start a transaction, so that we can later rollback any changes that were made.
-	//TODO: handle the case where we are already in a transaction (SAVEPOINT will sometimes fail it seems?)
-	if err := l.cfg.Handler.ComQuery(mysqlConn, "START TRANSACTION;", func(_ *sqltypes.Result, _ bool) error {
-		return nil
-	}); err != nil {
-		return err
+	if statement.AST != nil {
+		markBooleanFields(fields, booleanColumns(mysqlConn, statement.AST, query))
 	}
-	// We need to defer the rollback, so that it will always be executed.
-	defer func() {
-		_ = l.cfg.Handler.ComQuery(mysqlConn, "ROLLBACK;", func(_ *sqltypes.Result, _ bool) error {
-			return nil
-		})
-	}()
-	// Execute the statement, and send the description.
-	if err := l.comQuery(mysqlConn, statement, func(res *sqltypes.Result, more bool) error {
-		if res != nil {
-			if err := connection.Send(conn, messages.RowDescription{
-				Fields: res.Fields,
-			}); err != nil {
-				return err
-			}
-		}
-		return nil
-	}); err != nil {
+	formatCodes, err := columnFormatCodes(resultFormatCodes, len(fields))
+	if err != nil {
 		return err
 	}
-
-	return nil
+	return connection.Send(conn, messages.RowDescription{
+		Fields:      fields,
+		FormatCodes: formatCodes,
+	})
 }
 
 // handledPSQLCommands handles the special PSQL commands, such as \l and \dt.
-func (l *Listener) handledPSQLCommands(conn net.Conn, mysqlConn *mysql.Conn, statement string) (bool, error) {
+func (l *Listener) handledPSQLCommands(conn net.Conn, mysqlConn *mysql.Conn, transaction *transactionState, statement string) (bool, error) {
 	statement = strings.ToLower(statement)
 	// Command: \l
 	if statement == "select d.datname as \"name\",\n       pg_catalog.pg_get_userbyid(d.datdba) as \"owner\",\n       pg_catalog.pg_encoding_to_char(d.encoding) as \"encoding\",\n       d.datcollate as \"collate\",\n       d.datctype as \"ctype\",\n       d.daticulocale as \"icu locale\",\n       case d.datlocprovider when 'c' then 'libc' when 'i' then 'icu' end as \"locale provider\",\n       pg_catalog.array_to_string(d.datacl, e'\\n') as \"access privileges\"\nfrom pg_catalog.pg_database d\norder by 1;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{`SELECT SCHEMA_NAME AS 'Name', 'postgres' AS 'Owner', 'UTF8' AS 'Encoding', 'English_United States.1252' AS 'Collate', 'English_United States.1252' AS 'Ctype', '' AS 'ICU Locale', 'libc' AS 'Locale Provider', '' AS 'Access privileges' FROM INFORMATION_SCHEMA.SCHEMATA ORDER BY 1;`, nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{`SELECT SCHEMA_NAME AS 'Name', 'postgres' AS 'Owner', 'UTF8' AS 'Encoding', 'English_United States.1252' AS 'Collate', 'English_United States.1252' AS 'Ctype', '' AS 'ICU Locale', 'libc' AS 'Locale Provider', '' AS 'Access privileges' FROM INFORMATION_SCHEMA.SCHEMATA ORDER BY 1;`, nil, nil})
 	}
 	// Command: \dt
 	if statement == "select n.nspname as \"schema\",\n  c.relname as \"name\",\n  case c.relkind when 'r' then 'table' when 'v' then 'view' when 'm' then 'materialized view' when 'i' then 'index' when 's' then 'sequence' when 't' then 'toast table' when 'f' then 'foreign table' when 'p' then 'partitioned table' when 'i' then 'partitioned index' end as \"type\",\n  pg_catalog.pg_get_userbyid(c.relowner) as \"owner\"\nfrom pg_catalog.pg_class c\n     left join pg_catalog.pg_namespace n on n.oid = c.relnamespace\n     left join pg_catalog.pg_am am on am.oid = c.relam\nwhere c.relkind in ('r','p','')\n      and n.nspname <> 'pg_catalog'\n      and n.nspname !~ '^pg_toast'\n      and n.nspname <> 'information_schema'\n  and pg_catalog.pg_table_is_visible(c.oid)\norder by 1,2;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{`SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'table' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'BASE TABLE' ORDER BY 2;`, nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{`SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'table' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'BASE TABLE' ORDER BY 2;`, nil, nil})
 	}
 	// Command: \d
 	if statement == "select n.nspname as \"schema\",\n  c.relname as \"name\",\n  case c.relkind when 'r' then 'table' when 'v' then 'view' when 'm' then 'materialized view' when 'i' then 'index' when 's' then 'sequence' when 't' then 'toast table' when 'f' then 'foreign table' when 'p' then 'partitioned table' when 'i' then 'partitioned index' end as \"type\",\n  pg_catalog.pg_get_userbyid(c.relowner) as \"owner\"\nfrom pg_catalog.pg_class c\n     left join pg_catalog.pg_namespace n on n.oid = c.relnamespace\n     left join pg_catalog.pg_am am on am.oid = c.relam\nwhere c.relkind in ('r','p','v','m','s','f','')\n      and n.nspname <> 'pg_catalog'\n      and n.nspname !~ '^pg_toast'\n      and n.nspname <> 'information_schema'\n  and pg_catalog.pg_table_is_visible(c.oid)\norder by 1,2;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{`SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'table' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'BASE TABLE' ORDER BY 2;`, nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{`SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'table' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'BASE TABLE' ORDER BY 2;`, nil, nil})
 	}
 	// Command: \d table_name
 	if strings.HasPrefix(statement, "select c.oid,\n  n.nspname,\n  c.relname\nfrom pg_catalog.pg_class c\n     left join pg_catalog.pg_namespace n on n.oid = c.relnamespace\nwhere c.relname operator(pg_catalog.~) '^(") && strings.HasSuffix(statement, ")$' collate pg_catalog.default\n  and pg_catalog.pg_table_is_visible(c.oid)\norder by 2, 3;") {
@@ -456,20 +882,20 @@ func (l *Listener) handledPSQLCommands(conn net.Conn, mysqlConn *mysql.Conn, sta
 	}
 	// Command: \dn
 	if statement == "select n.nspname as \"name\",\n  pg_catalog.pg_get_userbyid(n.nspowner) as \"owner\"\nfrom pg_catalog.pg_namespace n\nwhere n.nspname !~ '^pg_' and n.nspname <> 'information_schema'\norder by 1;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{"SELECT 'public' AS 'Name', 'pg_database_owner' AS 'Owner';", nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{"SELECT 'public' AS 'Name', 'pg_database_owner' AS 'Owner';", nil, nil})
 	}
 	// Command: \df
 	if statement == "select n.nspname as \"schema\",\n  p.proname as \"name\",\n  pg_catalog.pg_get_function_result(p.oid) as \"result data type\",\n  pg_catalog.pg_get_function_arguments(p.oid) as \"argument data types\",\n case p.prokind\n  when 'a' then 'agg'\n  when 'w' then 'window'\n  when 'p' then 'proc'\n  else 'func'\n end as \"type\"\nfrom pg_catalog.pg_proc p\n     left join pg_catalog.pg_namespace n on n.oid = p.pronamespace\nwhere pg_catalog.pg_function_is_visible(p.oid)\n      and n.nspname <> 'pg_catalog'\n      and n.nspname <> 'information_schema'\norder by 1, 2, 4;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{"SELECT '' AS 'Schema', '' AS 'Name', '' AS 'Result data type', '' AS 'Argument data types', '' AS 'Type' FROM dual LIMIT 0;", nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{"SELECT '' AS 'Schema', '' AS 'Name', '' AS 'Result data type', '' AS 'Argument data types', '' AS 'Type' FROM dual LIMIT 0;", nil, nil})
 	}
 	// Command: \dv
 	if statement == "select n.nspname as \"schema\",\n  c.relname as \"name\",\n  case c.relkind when 'r' then 'table' when 'v' then 'view' when 'm' then 'materialized view' when 'i' then 'index' when 's' then 'sequence' when 't' then 'toast table' when 'f' then 'foreign table' when 'p' then 'partitioned table' when 'i' then 'partitioned index' end as \"type\",\n  pg_catalog.pg_get_userbyid(c.relowner) as \"owner\"\nfrom pg_catalog.pg_class c\n     left join pg_catalog.pg_namespace n on n.oid = c.relnamespace\nwhere c.relkind in ('v','')\n      and n.nspname <> 'pg_catalog'\n      and n.nspname !~ '^pg_toast'\n      and n.nspname <> 'information_schema'\n  and pg_catalog.pg_table_is_visible(c.oid)\norder by 1,2;" {
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{"SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'view' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'VIEW' ORDER BY 2;", nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{"SELECT 'public' AS 'Schema', TABLE_NAME AS 'Name', 'view' AS 'Type', 'postgres' AS 'Owner' FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = database() AND TABLE_TYPE = 'VIEW' ORDER BY 2;", nil, nil})
 	}
 	// Command: \du
 	if statement == "select r.rolname, r.rolsuper, r.rolinherit,\n  r.rolcreaterole, r.rolcreatedb, r.rolcanlogin,\n  r.rolconnlimit, r.rolvaliduntil,\n  array(select b.rolname\n        from pg_catalog.pg_auth_members m\n        join pg_catalog.pg_roles b on (m.roleid = b.oid)\n        where m.member = r.oid) as memberof\n, r.rolreplication\n, r.rolbypassrls\nfrom pg_catalog.pg_roles r\nwhere r.rolname !~ '^pg_'\norder by 1;" {
 		// We don't support users yet, so we'll just return nothing for now
-		return true, l.execute(conn, mysqlConn, ConvertedQuery{"SELECT '' FROM dual LIMIT 0;", nil})
+		return true, l.execute(conn, mysqlConn, transaction, ConvertedQuery{"SELECT '' FROM dual LIMIT 0;", nil, nil})
 	}
 	return false, nil
 }
@@ -477,48 +903,60 @@ func (l *Listener) handledPSQLCommands(conn net.Conn, mysqlConn *mysql.Conn, sta
 // endOfMessages should be called from HandleConnection or a function within HandleConnection. This represents the end
 // of the message slice, which may occur naturally (all relevant response messages have been sent) or on error. Once
 // endOfMessages has been called, no further messages should be sent, and the connection loop should wait for the next
-// query. A nil error should be provided if this is being called naturally.
-func (l *Listener) endOfMessages(conn net.Conn, err error) {
+// query. A nil error should be provided if this is being called naturally. An error fails the current transaction
+// block, if there is one. Any notifications that the connection has received are sent before the ReadyForQuery.
+func (l *Listener) endOfMessages(conn net.Conn, transaction *transactionState, err error) {
 	if err != nil {
+		transaction.Failed()
 		l.sendError(conn, err)
 	}
-	if sendErr := connection.Send(conn, messages.ReadyForQuery{
-		Indicator: messages.ReadyForQueryTransactionIndicator_Idle,
+	if sendErr := transaction.notifications.Ready(messages.ReadyForQuery{
+		Indicator: transaction.Indicator(),
 	}); sendErr != nil {
 		// We panic here for the same reason as above.
 		panic(sendErr)
 	}
 }
 
+// pipelineError should be called from HandleConnection when an error occurs while processing a message of the extended
+// query protocol. Unlike endOfMessages, ReadyForQuery is not sent, as the client expects exactly one ReadyForQuery in
+// response to the Sync that ends its pipeline of messages. The caller must discard every message until that Sync.
+func (l *Listener) pipelineError(conn net.Conn, transaction *transactionState, err error) {
+	transaction.Failed()
+	l.sendError(conn, err)
+}
+
 // sendError sends the given error to the client. This should generally never be called directly.
 func (l *Listener) sendError(conn net.Conn, err error) {
 	fmt.Println(err.Error())
-	if sendErr := connection.Send(conn, messages.ErrorResponse{
-		Severity:     messages.ErrorResponseSeverity_Error,
-		SqlStateCode: "XX000", // internal_error for now
-		Message:      err.Error(),
-	}); sendErr != nil {
+	if sendErr := connection.Send(conn, errorResponse(err)); sendErr != nil {
 		// If we're unable to send anything to the connection, then there's something wrong with the connection and
 		// we should terminate it. This will be caught in HandleConnection's defer block.
 		panic(sendErr)
 	}
 }
 
-// convertQuery takes the given Postgres query, and converts it as an ast.ConvertedQuery that will work with the handler.
-func (l *Listener) convertQuery(query string) (ConvertedQuery, error) {
+// parseQuery parses the given Postgres query, which must contain a single statement.
+func (l *Listener) parseQuery(query string) (parser.Statement, error) {
 	s, err := parser.Parse(query)
 	if err != nil {
-		return ConvertedQuery{}, err
+		return parser.Statement{}, err
 	}
 	if len(s) > 1 {
-		return ConvertedQuery{}, fmt.Errorf("only a single statement at a time is currently supported")
+		return parser.Statement{}, sqlStateError{Code: "42601", Message: "cannot insert multiple commands into a prepared statement"}
 	}
-	vitessAST, err := ast.Convert(s[0])
+	return s[0], nil
+}
+
+// convertStatement converts the given parsed statement as an ast.ConvertedQuery that will work with the handler. The
+// query is the string that the statement was parsed from.
+func (l *Listener) convertStatement(query string, s parser.Statement) (ConvertedQuery, error) {
+	vitessAST, err := ast.Convert(s)
 	if err != nil {
 		return ConvertedQuery{}, err
 	}
 	if vitessAST == nil {
-		return ConvertedQuery{String: s[0].AST.String()}, nil
+		return ConvertedQuery{String: s.AST.String()}, nil
 	}
 	return ConvertedQuery{
 		String: query,
@@ -526,11 +964,91 @@ func (l *Listener) convertQuery(query string) (ConvertedQuery, error) {
 	}, nil
 }
 
+// prepare creates a prepared statement from the given Parse message. The types of any parameters that were not given
+// by the client are inferred from the statement.
+func (l *Listener) prepare(message messages.Parse) (PreparedStatementData, error) {
+	s, err := l.parseQuery(message.Query)
+	if err != nil {
+		return PreparedStatementData{}, err
+	}
+	// Notifications are tracked by the connection rather than the engine, and their statements take no parameters
+	if isNotificationStatement(s.AST) {
+		return PreparedStatementData{
+			Query:     ConvertedQuery{String: message.Query},
+			Statement: s,
+		}, nil
+	}
+	query, err := l.convertStatement(message.Query, s)
+	if err != nil {
+		return PreparedStatementData{}, err
+	}
+	parameterTypes, err := ast.PlaceholderTypes(s, parameterTypeHints(message.ParameterObjectIDs))
+	if err != nil {
+		return PreparedStatementData{}, err
+	}
+	return PreparedStatementData{
+		Query:          query,
+		Statement:      s,
+		ParameterTypes: parameterTypes,
+	}, nil
+}
+
 // comQuery is a shortcut that determines which version of ComQuery to call based on whether the query has been parsed.
+// The fields of boolean columns are marked before they're given to the callback. If the query fails after a
+// CancelRequest was accepted for the connection, then errQueryCanceled is returned.
 func (l *Listener) comQuery(mysqlConn *mysql.Conn, query ConvertedQuery, callback func(res *sqltypes.Result, more bool) error) error {
+	if booleans := booleanColumns(mysqlConn, query.AST, query.String); booleans != nil {
+		resultCallback := callback
+		callback = func(res *sqltypes.Result, more bool) error {
+			markBooleanFields(res.Fields, booleans)
+			return resultCallback(res, more)
+		}
+	}
+	processID := int32(mysqlConn.ConnectionID)
+	beginQuery(processID)
+	var err error
 	if query.AST == nil {
-		return l.cfg.Handler.ComQuery(mysqlConn, query.String, callback)
+		err = l.cfg.Handler.ComQuery(mysqlConn, query.String, callback)
+	} else if len(query.BindVariables) > 0 {
+		// Like a MySQL prepared statement, the handler prepares the MySQL form of the statement and then binds its
+		// variables, so that the engine substitutes the values wherever the bind variables appear
+		err = l.cfg.Handler.ComStmtExecute(mysqlConn, &mysql.PrepareData{
+			PrepareStmt: sqlparser.String(query.AST),
+			ParamsCount: uint16(len(query.BindVariables)),
+			BindVars:    query.BindVariables,
+		}, func(res *sqltypes.Result) error {
+			return callback(res, false)
+		})
 	} else {
-		return l.cfg.Handler.ComParsedQuery(mysqlConn, query.String, query.AST, callback)
+		err = l.cfg.Handler.ComParsedQuery(mysqlConn, query.String, query.AST, callback)
+	}
+	if err != nil && takeCanceled(processID) {
+		return errQueryCanceled
+	}
+	if err != nil && l.isTerminating(mysqlConn.ConnectionID) {
+		return errAdminShutdown
+	}
+	return withStatementTable(query.AST, err)
+}
+
+// session returns the engine session of the given connection, along with a context for reading from it.
+func (l *Listener) session(mysqlConn *mysql.Conn) (*sql.Context, error) {
+	runningServer, _ := sqlserver.GetRunningServer()
+	if runningServer == nil {
+		return nil, fmt.Errorf("no server is running")
+	}
+	var session sql.Session
+	if err := runningServer.SessionManager().Iter(func(s sql.Session) (bool, error) {
+		if s.ID() == mysqlConn.ConnectionID {
+			session = s
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("connection %d does not have a session", mysqlConn.ConnectionID)
 	}
+	return sql.NewContext(context.Background(), sql.WithSession(session)), nil
 }
@@ -0,0 +1,147 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/types"
+	"github.com/dolthub/vitess/go/mysql"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// clientMinMessages is the session variable that controls which notices are sent to the client.
+const clientMinMessages = "client_min_messages"
+
+// clientMinMessagesLevels are the values of client_min_messages, from the least to the most severe. A notice is sent
+// when its severity is at least as severe as the session's value.
+var clientMinMessagesLevels = []string{"debug5", "debug4", "debug3", "debug2", "debug1", "log", "notice", "warning", "error"}
+
+func init() {
+	sql.SystemVariables.AddSystemVariables([]sql.SystemVariable{
+		{
+			Name:    clientMinMessages,
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemEnumType(clientMinMessages, clientMinMessagesLevels...),
+			Default: "notice",
+		},
+	})
+}
+
+// notice is a message that is sent to the client without affecting the statement, such as an engine warning.
+type notice struct {
+	Severity messages.ErrorResponseSeverity
+	Code     string
+	Message  string
+}
+
+// noticeLevel returns the position of the given severity within clientMinMessagesLevels. Severities that are always
+// sent, such as INFO, return a position past the end.
+func noticeLevel(severity messages.ErrorResponseSeverity) int {
+	severityName := strings.ToLower(string(severity))
+	if severity == messages.ErrorResponseSeverity_Debug {
+		// DEBUG on its own is the same as DEBUG2
+		severityName = "debug2"
+	}
+	for i, level := range clientMinMessagesLevels {
+		if level == severityName {
+			return i
+		}
+	}
+	return len(clientMinMessagesLevels)
+}
+
+// warningSQLStates are the SQLSTATE codes of the engine's warnings, keyed by their MySQL error code. All other
+// warnings use the general warning code.
+var warningSQLStates = map[int]string{
+	mysql.ERDupEntry:                    "23505", // unique_violation
+	mysql.ERBadNullError:                "23502", // not_null_violation
+	mysql.ERWarnDataOutOfRange:          "22003", // numeric_value_out_of_range
+	mysql.ERWarnDataTruncated:           "01004", // string_data_right_truncation
+	mysql.ERTruncatedWrongValue:         "22P02", // invalid_text_representation
+	mysql.ERTruncatedWrongValueForField: "22P02", // invalid_text_representation
+}
+
+// sendWarnings sends the warnings of the statement that was just run to the client.
+func (l *Listener) sendWarnings(conn net.Conn, mysqlConn *mysql.Conn) error {
+	if l.cfg.Handler.WarningCount(mysqlConn) == 0 {
+		return nil
+	}
+	ctx, err := l.session(mysqlConn)
+	if err != nil {
+		return err
+	}
+	// The session lists the most recent warning first
+	warnings := ctx.Session.Warnings()
+	notices := make([]notice, 0, len(warnings))
+	for i := len(warnings) - 1; i >= 0; i-- {
+		n := notice{
+			Severity: messages.ErrorResponseSeverity_Warning,
+			Code:     "01000", // warning
+			Message:  warnings[i].Message,
+		}
+		if warnings[i].Level == "Note" {
+			n.Severity = messages.ErrorResponseSeverity_Notice
+			n.Code = "00000" // successful_completion
+		}
+		if sqlState, ok := warningSQLStates[warnings[i].Code]; ok {
+			n.Code = sqlState
+		}
+		notices = append(notices, n)
+	}
+	// The engine only clears the warnings that existed at the previous call, so this marks the sent warnings to be
+	// cleared by the next statement
+	ctx.ClearWarnings()
+	return l.sendNotices(conn, mysqlConn, notices)
+}
+
+// sendNotices sends the given notices to the client as NoticeResponse messages, skipping the ones that are less severe
+// than the session's client_min_messages.
+func (l *Listener) sendNotices(conn net.Conn, mysqlConn *mysql.Conn, notices []notice) error {
+	if len(notices) == 0 {
+		return nil
+	}
+	ctx, err := l.session(mysqlConn)
+	if err != nil {
+		return err
+	}
+	minMessages, err := ctx.GetSessionVariable(ctx, clientMinMessages)
+	if err != nil {
+		return err
+	}
+	minLevel := noticeLevel(messages.ErrorResponseSeverity(fmt.Sprint(minMessages)))
+	for _, n := range notices {
+		if noticeLevel(n.Severity) < minLevel {
+			continue
+		}
+		if err := connection.Send(conn, messages.NoticeResponse{
+			Fields: []messages.NoticeResponseField{
+				{Code: 'S', Value: string(n.Severity)},
+				{Code: 'V', Value: string(n.Severity)},
+				{Code: 'C', Value: n.Code},
+				{Code: 'M', Value: n.Message},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
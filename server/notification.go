@@ -0,0 +1,388 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/expression"
+	"github.com/dolthub/go-mysql-server/sql/types"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+)
+
+// maxNotificationPayload is the length at which a payload is rejected, which matches Postgres' default.
+const maxNotificationPayload = 8000
+
+// notificationSessions contains the notification session of each live connection, keyed by the connection's ID.
+var notificationSessions = struct {
+	sync.Mutex
+	sessions map[uint32]*notificationSession
+}{sessions: make(map[uint32]*notificationSession)}
+
+// notificationSession tracks the channels that a connection is listening on, along with the notifications that it
+// has sent and received. Notifications and changes to the listened channels are pending until the connection's
+// transaction commits, and are discarded if it rolls back. Received notifications are sent to the client whenever the
+// connection is idle, which is either right before a ReadyForQuery, or while waiting for the client's next query.
+type notificationSession struct {
+	processID int32
+	conn      net.Conn
+	// writeMu is held while notifications are being sent from outside of the connection's own goroutine, so that they
+	// are never interleaved with the connection's other messages.
+	writeMu sync.Mutex
+
+	mu             sync.Mutex
+	channels       map[string]struct{}
+	pendingActions []listenAction
+	pending        []messages.NotificationResponse
+	received       []messages.NotificationResponse
+	idle           bool
+	delivering     bool
+	wake           chan struct{}
+	closed         chan struct{}
+}
+
+// listenAction is a pending LISTEN or UNLISTEN. An empty channel with Listen set to false is an UNLISTEN *.
+type listenAction struct {
+	Channel string
+	Listen  bool
+}
+
+// newNotificationSession creates and registers the notification session for the connection with the given ID.
+func newNotificationSession(connectionID uint32, conn net.Conn) *notificationSession {
+	session := &notificationSession{
+		processID: int32(connectionID),
+		conn:      conn,
+		channels:  make(map[string]struct{}),
+		wake:      make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+	notificationSessions.Lock()
+	defer notificationSessions.Unlock()
+	notificationSessions.sessions[connectionID] = session
+	return session
+}
+
+// Close unregisters the session, so that it no longer receives notifications.
+func (ns *notificationSession) Close() {
+	notificationSessions.Lock()
+	delete(notificationSessions.sessions, uint32(ns.processID))
+	notificationSessions.Unlock()
+	close(ns.closed)
+}
+
+// Listen adds a pending LISTEN for the given channel.
+func (ns *notificationSession) Listen(channel string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.pendingActions = append(ns.pendingActions, listenAction{Channel: channel, Listen: true})
+}
+
+// Unlisten adds a pending UNLISTEN for the given channel. All channels are unlistened when all is true.
+func (ns *notificationSession) Unlisten(channel string, all bool) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if all {
+		channel = ""
+	}
+	ns.pendingActions = append(ns.pendingActions, listenAction{Channel: channel, Listen: false})
+}
+
+// Notify adds a pending notification. Like Postgres, identical notifications within the same transaction are only
+// sent once.
+func (ns *notificationSession) Notify(channel string, payload string) error {
+	if len(channel) == 0 {
+		return sqlStateError{Code: "22023", Message: "channel name cannot be empty"}
+	}
+	if len(channel) > 63 {
+		return sqlStateError{Code: "22023", Message: "channel name too long"}
+	}
+	if len(payload) >= maxNotificationPayload {
+		return sqlStateError{Code: "22023", Message: "payload string too long"}
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	for _, notification := range ns.pending {
+		if notification.Channel == channel && notification.Payload == payload {
+			return nil
+		}
+	}
+	ns.pending = append(ns.pending, messages.NotificationResponse{
+		ProcessID: ns.processID,
+		Channel:   channel,
+		Payload:   payload,
+	})
+	return nil
+}
+
+// Commit applies the pending changes to the listened channels, and delivers the pending notifications to every
+// session that is listening on their channels.
+func (ns *notificationSession) Commit() {
+	ns.mu.Lock()
+	for _, action := range ns.pendingActions {
+		switch {
+		case action.Listen:
+			ns.channels[action.Channel] = struct{}{}
+		case len(action.Channel) == 0:
+			ns.channels = make(map[string]struct{})
+		default:
+			delete(ns.channels, action.Channel)
+		}
+	}
+	if len(ns.channels) > 0 && !ns.delivering {
+		ns.delivering = true
+		go ns.deliver()
+	}
+	pending := ns.pending
+	ns.pendingActions = nil
+	ns.pending = nil
+	ns.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	notificationSessions.Lock()
+	defer notificationSessions.Unlock()
+	for _, session := range notificationSessions.sessions {
+		session.receive(pending)
+	}
+}
+
+// Rollback discards the pending changes to the listened channels, along with the pending notifications.
+func (ns *notificationSession) Rollback() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.pendingActions = nil
+	ns.pending = nil
+}
+
+// receive queues the notifications whose channels are being listened on, and wakes the delivery goroutine.
+func (ns *notificationSession) receive(notifications []messages.NotificationResponse) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	queued := false
+	for _, notification := range notifications {
+		if _, ok := ns.channels[notification.Channel]; ok {
+			ns.received = append(ns.received, notification)
+			queued = true
+		}
+	}
+	if queued {
+		select {
+		case ns.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Busy marks the connection as processing messages, so that notifications are no longer sent to the client. This
+// waits for any notifications that are currently being sent.
+func (ns *notificationSession) Busy() {
+	ns.writeMu.Lock()
+	defer ns.writeMu.Unlock()
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.idle = false
+}
+
+// Ready sends the received notifications, followed by the given ReadyForQuery message. The connection is then idle,
+// so that notifications are sent as they're received.
+func (ns *notificationSession) Ready(readyForQuery messages.ReadyForQuery) error {
+	ns.mu.Lock()
+	received := ns.received
+	ns.received = nil
+	ns.mu.Unlock()
+	for _, notification := range received {
+		if err := connection.Send(ns.conn, notification); err != nil {
+			return err
+		}
+	}
+	if err := connection.Send(ns.conn, readyForQuery); err != nil {
+		return err
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.idle = true
+	if len(ns.received) > 0 {
+		select {
+		case ns.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// deliver sends notifications to the client as they're received while the connection is idle. This runs until the
+// session is closed.
+func (ns *notificationSession) deliver() {
+	for {
+		select {
+		case <-ns.closed:
+			return
+		case <-ns.wake:
+		}
+		ns.writeMu.Lock()
+		ns.mu.Lock()
+		var received []messages.NotificationResponse
+		if ns.idle {
+			received = ns.received
+			ns.received = nil
+		}
+		ns.mu.Unlock()
+		for _, notification := range received {
+			if err := connection.Send(ns.conn, notification); err != nil {
+				// The connection's own goroutine will notice the broken connection on its next read
+				break
+			}
+		}
+		ns.writeMu.Unlock()
+	}
+}
+
+// notificationStatement handles LISTEN, UNLISTEN, and NOTIFY. Like other statements, their effects only take place
+// once the transaction commits.
+func (l *Listener) notificationStatement(conn net.Conn, transaction *transactionState, queryString string, stmt tree.Statement) error {
+	query := ConvertedQuery{String: queryString}
+	if _, err := transaction.Check(query); err != nil {
+		return err
+	}
+	switch stmt := stmt.(type) {
+	case *tree.Listen:
+		transaction.notifications.Listen(string(stmt.ChannelName))
+	case *tree.Unlisten:
+		transaction.notifications.Unlisten(string(stmt.ChannelName), stmt.All)
+	case *tree.Notify:
+		if err := transaction.notifications.Notify(string(stmt.ChannelName), stmt.Payload); err != nil {
+			return err
+		}
+	}
+	transaction.Executed(query)
+	return connection.Send(conn, messages.CommandComplete{
+		Query: queryString,
+		Rows:  0,
+	})
+}
+
+// isNotificationStatement returns whether the given statement is LISTEN, UNLISTEN, or NOTIFY.
+func isNotificationStatement(stmt tree.Statement) bool {
+	switch stmt.(type) {
+	case *tree.Listen, *tree.Unlisten, *tree.Notify:
+		return true
+	default:
+		return false
+	}
+}
+
+// findNotificationSession returns the notification session of the connection with the given ID.
+func findNotificationSession(connectionID uint32) (*notificationSession, error) {
+	notificationSessions.Lock()
+	defer notificationSessions.Unlock()
+	session, ok := notificationSessions.sessions[connectionID]
+	if !ok {
+		return nil, fmt.Errorf("notifications are not available on this connection")
+	}
+	return session, nil
+}
+
+// PgNotify is the pg_notify function, which sends a notification like NOTIFY. Unlike NOTIFY, the channel and payload
+// may be any expression.
+type PgNotify struct {
+	expression.BinaryExpression
+}
+
+var _ sql.FunctionExpression = (*PgNotify)(nil)
+var _ sql.CollationCoercible = (*PgNotify)(nil)
+
+// NewPgNotify creates a new PgNotify expression.
+func NewPgNotify(channel sql.Expression, payload sql.Expression) sql.Expression {
+	return &PgNotify{expression.BinaryExpression{Left: channel, Right: payload}}
+}
+
+// FunctionName implements the interface sql.FunctionExpression.
+func (p *PgNotify) FunctionName() string {
+	return "pg_notify"
+}
+
+// Description implements the interface sql.FunctionExpression.
+func (p *PgNotify) Description() string {
+	return "sends a notification event to the listeners of the given channel."
+}
+
+// Eval implements the interface sql.Expression.
+func (p *PgNotify) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	channel, err := p.Left.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := p.Right.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		channel = ""
+	}
+	if payload == nil {
+		payload = ""
+	}
+	channel, _, err = types.LongText.Convert(channel)
+	if err != nil {
+		return nil, err
+	}
+	payload, _, err = types.LongText.Convert(payload)
+	if err != nil {
+		return nil, err
+	}
+	session, err := findNotificationSession(ctx.Session.ID())
+	if err != nil {
+		return nil, err
+	}
+	if err = session.Notify(channel.(string), payload.(string)); err != nil {
+		return nil, err
+	}
+	return "", nil
+}
+
+// String implements the interface fmt.Stringer.
+func (p *PgNotify) String() string {
+	return fmt.Sprintf("%s(%s, %s)", p.FunctionName(), p.Left, p.Right)
+}
+
+// IsNullable implements the interface sql.Expression.
+func (p *PgNotify) IsNullable() bool {
+	return false
+}
+
+// WithChildren implements the interface sql.Expression.
+func (p *PgNotify) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(p, len(children), 2)
+	}
+	return NewPgNotify(children[0], children[1]), nil
+}
+
+// Type implements the interface sql.Expression.
+func (p *PgNotify) Type() sql.Type {
+	return types.LongText
+}
+
+// CollationCoercibility implements the interface sql.CollationCoercible.
+func (*PgNotify) CollationCoercibility(ctx *sql.Context) (collation sql.CollationID, coercibility byte) {
+	return sql.Collation_binary, 5
+}
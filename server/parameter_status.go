@@ -0,0 +1,222 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/go-mysql-server/sql/types"
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// reportedParameters are the configuration parameters that are sent to the client through ParameterStatus messages,
+// both when the connection starts and whenever they're set afterward. Drivers rely on these to decide how values are
+// formatted, so they're named exactly as in Postgres, while the session variables that hold them are lowercase.
+var reportedParameters = []string{
+	"application_name",
+	"client_encoding",
+	"DateStyle",
+	"integer_datetimes",
+	"IntervalStyle",
+	"is_superuser",
+	"server_encoding",
+	"server_version",
+	"session_authorization",
+	"standard_conforming_strings",
+	"TimeZone",
+}
+
+func init() {
+	sql.SystemVariables.AddSystemVariables([]sql.SystemVariable{
+		{
+			Name:    "application_name",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemStringType("application_name"),
+			Default: "",
+		},
+		{
+			// Values are always sent as UTF8, so other encodings can't be chosen
+			Name:    "client_encoding",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemEnumType("client_encoding", "UTF8"),
+			Default: "UTF8",
+		},
+		{
+			Name:    "datestyle",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemStringType("datestyle"),
+			Default: "ISO, MDY",
+		},
+		{
+			Name:    "integer_datetimes",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: false,
+			Type:    types.NewSystemStringType("integer_datetimes"),
+			Default: "on",
+		},
+		{
+			Name:    "intervalstyle",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemEnumType("intervalstyle", "postgres", "postgres_verbose", "sql_standard", "iso_8601"),
+			Default: "postgres",
+		},
+		{
+			Name:    "is_superuser",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: false,
+			Type:    types.NewSystemStringType("is_superuser"),
+			Default: "on",
+		},
+		{
+			Name:    "server_encoding",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: false,
+			Type:    types.NewSystemStringType("server_encoding"),
+			Default: "UTF8",
+		},
+		{
+			Name:    "server_version",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: false,
+			Type:    types.NewSystemStringType("server_version"),
+			Default: "15.0",
+		},
+		{
+			// This is set to the connection's user once the connection starts
+			Name:    "session_authorization",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemStringType("session_authorization"),
+			Default: "",
+		},
+		{
+			// The parser always treats backslashes within strings literally, so this can't be turned off
+			Name:    "standard_conforming_strings",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemEnumType("standard_conforming_strings", "on"),
+			Default: "on",
+		},
+		{
+			Name:    "timezone",
+			Scope:   sql.SystemVariableScope_Both,
+			Dynamic: true,
+			Type:    types.NewSystemStringType("timezone"),
+			Default: "UTC",
+		},
+	})
+}
+
+// startupParameters sets the session variables from the parameters of the given StartupMessage, such as the
+// application_name. Parameters that don't name a session variable are ignored, as are the ones that describe the
+// connection itself. The session_authorization is set to the connection's user.
+func (l *Listener) startupParameters(mysqlConn *mysql.Conn, startupMessage messages.StartupMessage) error {
+	set := &sqlparser.Set{
+		Exprs: sqlparser.SetVarExprs{newSessionVarExpr("session_authorization", mysqlConn.User)},
+	}
+	for name, value := range startupMessage.Parameters {
+		name = strings.ToLower(name)
+		switch name {
+		case "user", "database", "options", "replication", "session_authorization":
+			continue
+		case "client_encoding":
+			// Clients such as psql may ask for the encoding to be chosen automatically, which is always UTF8
+			continue
+		}
+		if _, _, ok := sql.SystemVariables.GetGlobal(name); ok {
+			set.Exprs = append(set.Exprs, newSessionVarExpr(name, value))
+		}
+	}
+	return l.comQuery(mysqlConn, ConvertedQuery{String: sqlparser.String(set), AST: set}, func(*sqltypes.Result, bool) error {
+		return nil
+	})
+}
+
+// newSessionVarExpr returns the expression that sets the session variable with the given name to the given string.
+func newSessionVarExpr(name string, value string) *sqlparser.SetVarExpr {
+	return &sqlparser.SetVarExpr{
+		Scope: sqlparser.SetScope_Session,
+		Name:  &sqlparser.ColName{Name: sqlparser.NewColIdent(name)},
+		Expr:  sqlparser.NewStrVal([]byte(value)),
+	}
+}
+
+// sendParameterStatuses sends a ParameterStatus message with the current value of each of the given parameters. The
+// parameters must be named as in reportedParameters.
+func (l *Listener) sendParameterStatuses(conn net.Conn, mysqlConn *mysql.Conn, parameters []string) error {
+	if len(parameters) == 0 {
+		return nil
+	}
+	variables := make([]string, len(parameters))
+	for i, parameter := range parameters {
+		variables[i] = fmt.Sprintf("@@session.%s", strings.ToLower(parameter))
+	}
+	var values []string
+	if err := l.cfg.Handler.ComQuery(mysqlConn, fmt.Sprintf("SELECT %s;", strings.Join(variables, ", ")), func(res *sqltypes.Result, more bool) error {
+		for _, row := range res.Rows {
+			for _, value := range row {
+				values = append(values, value.ToString())
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(values) != len(parameters) {
+		return fmt.Errorf("expected %d parameter values but received %d", len(parameters), len(values))
+	}
+	for i, parameter := range parameters {
+		if err := connection.Send(conn, messages.ParameterStatus{
+			Name:  parameter,
+			Value: values[i],
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendChangedParameterStatuses sends a ParameterStatus message for each reported parameter that is set by the given
+// query. Nothing is sent for queries other than SET and RESET.
+func (l *Listener) sendChangedParameterStatuses(conn net.Conn, mysqlConn *mysql.Conn, query ConvertedQuery) error {
+	set, ok := query.AST.(*sqlparser.Set)
+	if !ok {
+		return nil
+	}
+	var parameters []string
+	for _, expr := range set.Exprs {
+		if expr.Name == nil {
+			continue
+		}
+		for _, parameter := range reportedParameters {
+			if strings.EqualFold(expr.Name.Name.String(), parameter) {
+				parameters = append(parameters, parameter)
+			}
+		}
+	}
+	return l.sendParameterStatuses(conn, mysqlConn, parameters)
+}
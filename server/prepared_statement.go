@@ -0,0 +1,244 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/lib/pq/oid"
+
+	"github.com/dolthub/doltgresql/postgres/messages"
+	"github.com/dolthub/doltgresql/postgres/parser/parser"
+	"github.com/dolthub/doltgresql/postgres/parser/sem/tree"
+	"github.com/dolthub/doltgresql/postgres/parser/types"
+	"github.com/dolthub/doltgresql/server/ast"
+)
+
+// PreparedStatementData represents a statement that was created by a Parse message. Statement is the parsed Postgres
+// statement that Query was converted from. ParameterTypes contains the type of each parameter, with a nil type
+// representing a parameter of an unknown type.
+type PreparedStatementData struct {
+	Query          ConvertedQuery
+	Statement      parser.Statement
+	ParameterTypes tree.PlaceholderTypes
+}
+
+// PortalData represents a portal that was created by a Bind message. Query holds the bound values of its parameters as
+// bind variables, while Statement is the parsed statement that it was bound from. ResultFormatCodes are the format
+// codes from the Bind message, which have not yet been matched to the result's columns. A portal that is executed with a row limit keeps its query running between Execute messages, so
+// that the remaining rows may be read by later Execute messages.
+type PortalData struct {
+	Query             ConvertedQuery
+	Statement         parser.Statement
+	ResultFormatCodes []int32
+	rows              *rowIterator
+	exhausted         bool
+}
+
+// Close stops the portal's query if it is still running. The portal is considered exhausted afterward.
+func (portal *PortalData) Close() {
+	if portal.rows != nil {
+		portal.rows.Close()
+		portal.rows = nil
+	}
+	portal.exhausted = true
+}
+
+// defaultMaxPreparedStatements is the number of named prepared statements that a connection may have at once, unless
+// the server was given a different limit.
+const defaultMaxPreparedStatements = 1000
+
+// addPreparedStatement adds the given prepared statement under the given name. Like Postgres, a new unnamed statement
+// replaces the previous one, while a named statement must be closed before its name may be reused. A named statement
+// is rejected once there are already the given maximum number of named statements, unless the maximum is zero.
+func addPreparedStatement(preparedStatements map[string]PreparedStatementData, name string, preparedStatement PreparedStatementData, maxPreparedStatements int) error {
+	if len(name) > 0 {
+		if _, ok := preparedStatements[name]; ok {
+			return sqlStateError{Code: "42P05", Message: fmt.Sprintf(`prepared statement "%s" already exists`, name)}
+		}
+		count := len(preparedStatements)
+		if _, ok := preparedStatements[""]; ok {
+			count--
+		}
+		if maxPreparedStatements > 0 && count >= maxPreparedStatements {
+			return sqlStateError{
+				Code:    "53400",
+				Message: fmt.Sprintf("cannot have more than %d prepared statements at once", maxPreparedStatements),
+			}
+		}
+	}
+	preparedStatements[name] = preparedStatement
+	return nil
+}
+
+// deallocatePreparedStatements removes the prepared statement with the given name. All named prepared statements are
+// removed when the name is empty, which is how DEALLOCATE ALL is represented.
+func deallocatePreparedStatements(preparedStatements map[string]PreparedStatementData, name string) error {
+	if len(name) == 0 {
+		for name = range preparedStatements {
+			if len(name) > 0 {
+				delete(preparedStatements, name)
+			}
+		}
+		return nil
+	}
+	if _, ok := preparedStatements[name]; !ok {
+		return preparedStatementNotFoundError(name)
+	}
+	delete(preparedStatements, name)
+	return nil
+}
+
+// preparedStatementNotFoundError returns the error for a prepared statement that does not exist.
+func preparedStatementNotFoundError(name string) error {
+	return sqlStateError{Code: "26000", Message: fmt.Sprintf(`prepared statement "%s" does not exist`, name)}
+}
+
+// portalNotFoundError returns the error for a portal that does not exist.
+func portalNotFoundError(name string) error {
+	return sqlStateError{Code: "34000", Message: fmt.Sprintf(`portal "%s" does not exist`, name)}
+}
+
+// closePortal closes and removes the portal with the given name, if it exists.
+func closePortal(portals map[string]*PortalData, name string) {
+	if portal, ok := portals[name]; ok {
+		portal.Close()
+		delete(portals, name)
+	}
+}
+
+// closePortals closes and removes every portal in the given map.
+func closePortals(portals map[string]*PortalData) {
+	for name, portal := range portals {
+		portal.Close()
+		delete(portals, name)
+	}
+}
+
+// drainPortals drains the query of every portal other than the given one, so that the session may run another
+// statement. A portal that is suspended keeps its query running, which must not overlap with any other statement.
+func drainPortals(portals map[string]*PortalData, except *PortalData) {
+	for _, portal := range portals {
+		if portal != except && portal.rows != nil {
+			portal.rows.Drain()
+		}
+	}
+}
+
+// parameterTypeHints converts the object IDs from a Parse message into type hints. An object ID of zero (or one that
+// is not recognized) leaves the type unspecified.
+func parameterTypeHints(objectIDs []int32) tree.PlaceholderTypes {
+	typeHints := make(tree.PlaceholderTypes, len(objectIDs))
+	for i, objectID := range objectIDs {
+		typeHints[i] = types.OidToType[oid.Oid(objectID)]
+	}
+	return typeHints
+}
+
+// parameterObjectIDs returns the object IDs of the given parameter types. Parameters with an unknown type are reported
+// with an object ID of zero (unspecified), which lets the client choose the type based on the value that it binds.
+func parameterObjectIDs(parameterTypes tree.PlaceholderTypes) []int32 {
+	objectIDs := make([]int32, len(parameterTypes))
+	for i, parameterType := range parameterTypes {
+		if parameterType != nil {
+			objectIDs[i] = int32(parameterType.Oid())
+		}
+	}
+	return objectIDs
+}
+
+// bindParameters returns the prepared statement's query with the parameters from the Bind message as the values of its
+// bind variables.
+func bindParameters(statement PreparedStatementData, message messages.Bind) (ConvertedQuery, error) {
+	if len(message.ParameterValues) != len(statement.ParameterTypes) {
+		return ConvertedQuery{}, fmt.Errorf(`bind message supplies %d parameters, but prepared statement "%s" requires %d`,
+			len(message.ParameterValues), message.SourcePreparedStatement, len(statement.ParameterTypes))
+	}
+	if len(statement.ParameterTypes) == 0 || statement.Query.AST == nil {
+		return statement.Query, nil
+	}
+	switch len(message.ParameterFormatCodes) {
+	case 0, 1, len(message.ParameterValues):
+	default:
+		return ConvertedQuery{}, fmt.Errorf(`bind message has %d parameter formats but %d parameters`,
+			len(message.ParameterFormatCodes), len(message.ParameterValues))
+	}
+
+	values := make([]tree.Datum, len(message.ParameterValues))
+	for i, parameterValue := range message.ParameterValues {
+		var formatCode int32
+		if len(message.ParameterFormatCodes) == 1 {
+			formatCode = message.ParameterFormatCodes[0]
+		} else if len(message.ParameterFormatCodes) > 1 {
+			formatCode = message.ParameterFormatCodes[i]
+		}
+		var err error
+		if values[i], err = decodeParameter(statement.ParameterTypes[i], formatCode, parameterValue); err != nil {
+			return ConvertedQuery{}, fmt.Errorf("invalid value for parameter $%d: %w", i+1, err)
+		}
+	}
+
+	bindVariables, err := ast.BindVariables(values)
+	if err != nil {
+		return ConvertedQuery{}, err
+	}
+	return ConvertedQuery{
+		String:        statement.Query.String,
+		AST:           statement.Query.AST,
+		BindVariables: bindVariables,
+	}, nil
+}
+
+// columnFormatCodes returns the format code of each column, given the format codes from a Bind message. No format codes
+// means that every column uses the text format, and a single format code applies to every column.
+func columnFormatCodes(formatCodes []int32, columnCount int) ([]int32, error) {
+	switch len(formatCodes) {
+	case 0:
+		return nil, nil
+	case 1:
+		columnFormatCodes := make([]int32, columnCount)
+		for i := range columnFormatCodes {
+			columnFormatCodes[i] = formatCodes[0]
+		}
+		return columnFormatCodes, nil
+	case columnCount:
+		return formatCodes, nil
+	default:
+		return nil, fmt.Errorf("bind message has %d result formats but query has %d columns", len(formatCodes), columnCount)
+	}
+}
+
+// decodeParameter decodes the given parameter value into a Datum of the given type. A nil type is treated as text, and
+// the engine will convert the value as needed.
+func decodeParameter(parameterType *types.T, formatCode int32, value messages.BindParameterValue) (tree.Datum, error) {
+	if value.IsNull {
+		return tree.DNull, nil
+	}
+	switch formatCode {
+	case messages.FormatCode_Text:
+		if parameterType == nil {
+			return tree.NewDString(string(value.Data)), nil
+		}
+		datum, _, err := tree.ParseAndRequireString(parameterType, string(value.Data), nil)
+		return datum, err
+	case messages.FormatCode_Binary:
+		if parameterType == nil {
+			return nil, fmt.Errorf("could not determine the data type of a parameter in the binary format")
+		}
+		return messages.DecodeBinary(parameterType.Oid(), value.Data)
+	default:
+		return nil, fmt.Errorf("unknown format code %d", formatCode)
+	}
+}
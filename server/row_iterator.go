@@ -0,0 +1,149 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+
+	"github.com/dolthub/vitess/go/mysql"
+	"github.com/dolthub/vitess/go/sqltypes"
+	"github.com/dolthub/vitess/go/vt/proto/query"
+)
+
+// errRowIteratorClosed is returned to the handler when a row iterator is closed before all of its rows have been read,
+// which stops the query.
+var errRowIteratorClosed = errors.New("the portal was closed before all of its rows were read")
+
+// rowIterator runs a query in the background so that its rows may be read a few at a time. The handler only returns
+// results through a callback, so the callback waits until its rows have been requested before returning. This keeps the
+// handler from producing rows faster than the client reads them, so that a large result is never held in memory. The
+// query only runs while its rows are being read, as it shares the connection's session with every other statement.
+type rowIterator struct {
+	results  chan *sqltypes.Result
+	resume   chan struct{}
+	closed   chan struct{}
+	done     chan struct{}
+	err      error
+	fields   []*query.Field
+	rows     [][]sqltypes.Value
+	affected uint64
+	finished bool
+}
+
+// newRowIterator starts running the given query, returning an iterator over its rows. The iterator must be closed
+// once it is no longer needed.
+func (l *Listener) newRowIterator(mysqlConn *mysql.Conn, query ConvertedQuery) *rowIterator {
+	iter := &rowIterator{
+		results: make(chan *sqltypes.Result),
+		resume:  make(chan struct{}),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(iter.done)
+		if !iter.wait() {
+			iter.err = errRowIteratorClosed
+			return
+		}
+		iter.err = l.comQuery(mysqlConn, query, func(res *sqltypes.Result, more bool) error {
+			select {
+			case iter.results <- res:
+			case <-iter.closed:
+				return errRowIteratorClosed
+			}
+			if !iter.wait() {
+				return errRowIteratorClosed
+			}
+			return nil
+		})
+	}()
+	return iter
+}
+
+// wait blocks the query until more rows are requested. Returns false if the iterator was closed instead.
+func (iter *rowIterator) wait() bool {
+	select {
+	case <-iter.resume:
+		return true
+	case <-iter.closed:
+		return false
+	}
+}
+
+// Next returns at most rowMax rows, with a rowMax of zero or less returning all of the remaining rows. The returned
+// bool is true once every row has been returned.
+func (iter *rowIterator) Next(rowMax int32) ([][]sqltypes.Value, bool, error) {
+	if err := iter.fill(rowMax); err != nil {
+		return nil, true, err
+	}
+	count := len(iter.rows)
+	if rowMax > 0 && count > int(rowMax) {
+		count = int(rowMax)
+	}
+	rows := iter.rows[:count]
+	iter.rows = iter.rows[count:]
+	return rows, iter.finished && len(iter.rows) == 0, nil
+}
+
+// Drain runs the query to completion, holding the rest of its rows in memory until they're read. Once drained, the
+// query no longer uses the session, so that the session may run other statements. Any error is returned by Next.
+func (iter *rowIterator) Drain() {
+	_ = iter.fill(0)
+}
+
+// fill runs the query until at least rowMax rows are held, or until the query is done, with a rowMax of zero or less
+// running the query to completion. Returns the query's error once it's done.
+func (iter *rowIterator) fill(rowMax int32) error {
+	for !iter.finished && (rowMax <= 0 || len(iter.rows) < int(rowMax)) {
+		// The query is always waiting for its next request while it's unfinished
+		iter.resume <- struct{}{}
+		select {
+		case res := <-iter.results:
+			if iter.fields == nil {
+				iter.fields = res.Fields
+			}
+			iter.rows = append(iter.rows, res.Rows...)
+			iter.affected = res.RowsAffected
+		case <-iter.done:
+			// The results are sent synchronously, so every result has been received once the query is done
+			iter.finished = true
+		}
+	}
+	if iter.finished {
+		return iter.err
+	}
+	return nil
+}
+
+// Fields returns the fields of the query's rows. This is nil until the first call to Next.
+func (iter *rowIterator) Fields() []*query.Field {
+	return iter.fields
+}
+
+// RowsAffected returns the number of rows that were affected by an INSERT, UPDATE, or DELETE query. This is only
+// accurate once every row has been returned.
+func (iter *rowIterator) RowsAffected() uint64 {
+	return iter.affected
+}
+
+// Close stops the query if it is still running, and waits for it to finish.
+func (iter *rowIterator) Close() {
+	select {
+	case <-iter.closed:
+	default:
+		close(iter.closed)
+	}
+	<-iter.done
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/secure/precis"
+)
+
+const (
+	// scramMechanism is the SASL mechanism for SCRAM-SHA-256.
+	scramMechanism = "SCRAM-SHA-256"
+	// scramPlusMechanism is the SASL mechanism for SCRAM-SHA-256 with channel binding, which is only offered over TLS.
+	scramPlusMechanism = "SCRAM-SHA-256-PLUS"
+	// scramChannelBinding is the only channel binding type that Postgres supports.
+	scramChannelBinding = "tls-server-end-point"
+	// scramIterations is the iteration count of newly created verifiers, which matches the Postgres default.
+	scramIterations = 4096
+	// scramSaltLength is the length of the salt of newly created verifiers, which matches the Postgres default.
+	scramSaltLength = 16
+	// scramNonceLength is the number of random bytes in the server's nonce.
+	scramNonceLength = 18
+)
+
+// scramVerifier is a stored SCRAM-SHA-256 password verifier. The password itself cannot be recovered from a verifier,
+// but a verifier is enough to check a client's proof that it knows the password.
+type scramVerifier struct {
+	Iterations int
+	Salt       []byte
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// newScramVerifier creates a verifier for the given password, using a random salt.
+func newScramVerifier(password string) (scramVerifier, error) {
+	salt := make([]byte, scramSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return scramVerifier{}, err
+	}
+	saltedPassword := pbkdf2.Key(scramNormalizePassword(password), salt, scramIterations, sha256.Size, sha256.New)
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	return scramVerifier{
+		Iterations: scramIterations,
+		Salt:       salt,
+		StoredKey:  storedKey[:],
+		ServerKey:  scramHMAC(saltedPassword, "Server Key"),
+	}, nil
+}
+
+// parseScramVerifier parses a verifier in the format that Postgres stores in pg_authid, which is
+// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey> with each binary value encoded in base64.
+func parseScramVerifier(str string) (scramVerifier, error) {
+	invalidErr := fmt.Errorf("invalid SCRAM-SHA-256 verifier")
+	parts := strings.Split(str, "$")
+	if len(parts) != 3 || parts[0] != scramMechanism {
+		return scramVerifier{}, invalidErr
+	}
+	iterationsStr, saltStr, ok := strings.Cut(parts[1], ":")
+	if !ok {
+		return scramVerifier{}, invalidErr
+	}
+	storedKeyStr, serverKeyStr, ok := strings.Cut(parts[2], ":")
+	if !ok {
+		return scramVerifier{}, invalidErr
+	}
+	iterations, err := strconv.Atoi(iterationsStr)
+	if err != nil || iterations <= 0 {
+		return scramVerifier{}, invalidErr
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltStr)
+	if err != nil {
+		return scramVerifier{}, invalidErr
+	}
+	storedKey, err := base64.StdEncoding.DecodeString(storedKeyStr)
+	if err != nil || len(storedKey) != sha256.Size {
+		return scramVerifier{}, invalidErr
+	}
+	serverKey, err := base64.StdEncoding.DecodeString(serverKeyStr)
+	if err != nil || len(serverKey) != sha256.Size {
+		return scramVerifier{}, invalidErr
+	}
+	return scramVerifier{
+		Iterations: iterations,
+		Salt:       salt,
+		StoredKey:  storedKey,
+		ServerKey:  serverKey,
+	}, nil
+}
+
+// String returns the verifier in the format that Postgres stores in pg_authid.
+func (v scramVerifier) String() string {
+	return fmt.Sprintf("%s$%d:%s$%s:%s", scramMechanism, v.Iterations, base64.StdEncoding.EncodeToString(v.Salt),
+		base64.StdEncoding.EncodeToString(v.StoredKey), base64.StdEncoding.EncodeToString(v.ServerKey))
+}
+
+// VerifyProof returns whether the client's proof for the given authentication message shows that the client knows
+// the password. The client's proof is its ClientKey XOR'd with its signature, so the ClientKey is recovered using the
+// signature, and then compared against the StoredKey.
+func (v scramVerifier) VerifyProof(authMessage string, proof []byte) bool {
+	clientSignature := scramHMAC(v.StoredKey, authMessage)
+	if len(proof) != len(clientSignature) {
+		return false
+	}
+	clientKey := make([]byte, len(proof))
+	for i := range proof {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	storedKey := sha256.Sum256(clientKey)
+	return hmac.Equal(storedKey[:], v.StoredKey)
+}
+
+// VerifyPassword returns whether the given password matches the verifier.
+func (v scramVerifier) VerifyPassword(password string) bool {
+	saltedPassword := pbkdf2.Key(scramNormalizePassword(password), v.Salt, v.Iterations, sha256.Size, sha256.New)
+	storedKey := sha256.Sum256(scramHMAC(saltedPassword, "Client Key"))
+	return hmac.Equal(storedKey[:], v.StoredKey) && hmac.Equal(scramHMAC(saltedPassword, "Server Key"), v.ServerKey)
+}
+
+// ServerSignature returns the signature that proves to the client that the server also knows the password.
+func (v scramVerifier) ServerSignature(authMessage string) []byte {
+	return scramHMAC(v.ServerKey, authMessage)
+}
+
+// scramHMAC returns the HMAC-SHA-256 of the given message.
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// scramNormalizePassword applies SASLprep to the password. Like Postgres, passwords that are not valid under SASLprep
+// are used as-is.
+func scramNormalizePassword(password string) []byte {
+	normalized, err := precis.OpaqueString.Bytes([]byte(password))
+	if err != nil {
+		return []byte(password)
+	}
+	return normalized
+}
+
+// scramNonce returns a new random nonce, which only contains printable characters.
+func scramNonce() (string, error) {
+	nonce := make([]byte, scramNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(nonce), nil
+}
+
+// scramChannelBindingData returns the channel binding data for tls-server-end-point, which is the hash of the server's
+// certificate. The hash function is the one that was used for the certificate's signature, except that MD5 and SHA-1
+// are upgraded to SHA-256.
+func scramChannelBindingData(cert tls.Certificate) ([]byte, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, fmt.Errorf("the server does not have a certificate")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	var h hash.Hash
+	switch leaf.SignatureAlgorithm {
+	case x509.SHA384WithRSA, x509.ECDSAWithSHA384, x509.SHA384WithRSAPSS:
+		h = sha512.New384()
+	case x509.SHA512WithRSA, x509.ECDSAWithSHA512, x509.SHA512WithRSAPSS:
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+	h.Write(leaf.Raw)
+	return h.Sum(nil), nil
+}
+
+// scramAttributes parses a SCRAM message, which is a comma-separated list of attributes in the form `a=value`. The
+// attributes are returned in order, and it is an error for any attribute to be malformed.
+func scramAttributes(message string) ([][2]string, error) {
+	var attributes [][2]string
+	for _, attribute := range strings.Split(message, ",") {
+		if len(attribute) < 2 || attribute[1] != '=' {
+			return nil, fmt.Errorf("malformed SCRAM message: %q", message)
+		}
+		attributes = append(attributes, [2]string{attribute[:1], attribute[2:]})
+	}
+	return attributes, nil
+}
@@ -17,13 +17,17 @@ package server
 import (
 	"context"
 	crand "crypto/rand"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"math/rand"
 	_ "net/http/pprof"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dolthub/dolt/go/cmd/dolt/cli"
 	"github.com/dolthub/dolt/go/cmd/dolt/commands"
@@ -71,6 +75,46 @@ const stdErrFlag = "--stderr"
 const stdOutAndErrFlag = "--out-and-err"
 const ignoreLocksFlag = "--ignore-lock-file"
 
+// authFileFlag is the path of the file containing the password verifier of each user. Authentication is disabled when
+// it is not given.
+const authFileFlag = "--auth-file"
+
+// hbaFileFlag is the path of the file containing the host-based authentication rules, which uses the same format as
+// pg_hba.conf.
+const hbaFileFlag = "--hba-file"
+
+// maxPreparedStatementsFlag is the number of named prepared statements that each connection may have at once.
+const maxPreparedStatementsFlag = "--max-prepared-statements"
+
+// socketDirFlag is the directory that the Unix socket is created in. The socket is not created when this is empty.
+const socketDirFlag = "--socket-dir"
+
+// socketPermissionsFlag are the access permissions of the Unix socket, written in octal.
+const socketPermissionsFlag = "--socket-permissions"
+
+// sslFlag determines whether clients may use SSL, and is one of "off", "on", or "require". SSL is only offered when the
+// server has a certificate.
+const sslFlag = "--ssl"
+
+// sslCertFileFlag is the path of the server's PEM-encoded certificate chain.
+const sslCertFileFlag = "--ssl-cert-file"
+
+// sslKeyFileFlag is the path of the server's PEM-encoded private key.
+const sslKeyFileFlag = "--ssl-key-file"
+
+// sslCAFileFlag is the path of the PEM-encoded certificate authorities that client certificates are verified against.
+const sslCAFileFlag = "--ssl-ca-file"
+
+// sslMinProtocolVersionFlag is the oldest TLS version that clients may use, such as "TLSv1.2".
+const sslMinProtocolVersionFlag = "--ssl-min-protocol-version"
+
+// shutdownTimeoutFlag is how long the server waits for running queries to finish when it shuts down, such as "30s".
+const shutdownTimeoutFlag = "--shutdown-timeout"
+
+// recordDirFlag is the directory that each connection's session is recorded to, so that it may be replayed later by the
+// replay command.
+const recordDirFlag = "--record-dir"
+
 // RunOnDisk starts the server based on the given args, while also using the local disk as the backing store.
 // The returned WaitGroup may be used to wait for the server to close.
 func RunOnDisk(args []string) (*int, *sync.WaitGroup) {
@@ -88,6 +132,101 @@ func RunInMemory(args []string) (*int, *sync.WaitGroup) {
 func runServer(args []string, fs filesys.Filesys) (*int, *sync.WaitGroup) {
 	wg := &sync.WaitGroup{}
 	ctx := context.Background()
+	// Replays run against a server that is already running, so the server isn't started
+	if len(args) > 0 && args[0] == replayCommand {
+		return intPointer(runReplay(args[1:])), wg
+	}
+	listenerCfg := newListenerConfig()
+	// Doltgres-specific flags are removed here, as the remaining args are parsed by Dolt, which would reject them
+	args, authFile, hasAuthFile := extractFlag(args, authFileFlag)
+	if hasAuthFile {
+		var err error
+		if listenerCfg.passwordVerifiers, err = loadAuthFile(authFile); err != nil {
+			cli.PrintErrln(color.RedString("Failed to load the auth file: %v", err))
+			return intPointer(1), wg
+		}
+	}
+	args, hbaFile, hasHBAFile := extractFlag(args, hbaFileFlag)
+	if hasHBAFile {
+		var err error
+		if listenerCfg.hbaRules, err = loadHBAFile(hbaFile); err != nil {
+			cli.PrintErrln(color.RedString("Failed to load the HBA file: %v", err))
+			return intPointer(1), wg
+		}
+	}
+	args, maxStatements, hasMaxStatements := extractFlag(args, maxPreparedStatementsFlag)
+	if hasMaxStatements {
+		var err error
+		if listenerCfg.maxPreparedStatements, err = strconv.Atoi(maxStatements); err != nil || listenerCfg.maxPreparedStatements < 0 {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %s", maxPreparedStatementsFlag, maxStatements))
+			return intPointer(1), wg
+		}
+	}
+	args, socketDir, hasSocketDir := extractFlag(args, socketDirFlag)
+	// The in-memory database is only used for testing/virtual environments, so it only has a socket when one is asked for
+	if _, inMemory := fs.(*filesys.InMemFS); !hasSocketDir && !inMemory && runtime.GOOS != "windows" {
+		socketDir = defaultSocketDirectory
+	}
+	args, permissions, hasPermissions := extractFlag(args, socketPermissionsFlag)
+	if hasPermissions {
+		var err error
+		if listenerCfg.socketPermissions, err = parseSocketPermissions(permissions); err != nil {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %s", socketPermissionsFlag, permissions))
+			return intPointer(1), wg
+		}
+	}
+	args, mode, hasMode := extractFlag(args, sslFlag)
+	ssl := sslConfig{mode: sslMode_On}
+	if hasMode {
+		var err error
+		if ssl.mode, err = parseSSLMode(mode); err != nil {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %s", sslFlag, mode))
+			return intPointer(1), wg
+		}
+	}
+	args, certFile, hasCertFile := extractFlag(args, sslCertFileFlag)
+	args, keyFile, hasKeyFile := extractFlag(args, sslKeyFileFlag)
+	if hasCertFile != hasKeyFile {
+		cli.PrintErrln(color.RedString("%s and %s must be given together", sslCertFileFlag, sslKeyFileFlag))
+		return intPointer(1), wg
+	}
+	args, caFile, _ := extractFlag(args, sslCAFileFlag)
+	args, minVersion, hasMinVersion := extractFlag(args, sslMinProtocolVersionFlag)
+	tlsMinVersion := uint16(defaultTLSMinVersion)
+	if hasMinVersion {
+		var err error
+		if tlsMinVersion, err = parseTLSVersion(minVersion); err != nil {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %s", sslMinProtocolVersionFlag, minVersion))
+			return intPointer(1), wg
+		}
+	}
+	if hasCertFile {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			cli.PrintErrln(color.RedString("Failed to load the SSL certificate: %v", err))
+			return intPointer(1), wg
+		}
+		if ssl.tlsConfig, err = newTLSConfig(certificate, caFile, tlsMinVersion); err != nil {
+			cli.PrintErrln(color.RedString("Failed to load the SSL certificate authorities: %v", err))
+			return intPointer(1), wg
+		}
+	}
+	args, timeout, hasTimeout := extractFlag(args, shutdownTimeoutFlag)
+	if hasTimeout {
+		var err error
+		if listenerCfg.shutdownTimeout, err = time.ParseDuration(timeout); err != nil || listenerCfg.shutdownTimeout < 0 {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %s", shutdownTimeoutFlag, timeout))
+			return intPointer(1), wg
+		}
+	}
+	args, recordDir, _ := extractFlag(args, recordDirFlag)
+	listenerCfg.recordDirectory = recordDir
+	if len(recordDir) > 0 {
+		if err := validateRecordDirectory(recordDir); err != nil {
+			cli.PrintErrln(color.RedString("Invalid value for %s: %v", recordDirFlag, err))
+			return intPointer(1), wg
+		}
+	}
 	// Inject the "sql-server" command if no other commands were given
 	if len(args) == 0 || (len(args) > 0 && strings.HasPrefix(args[0], "-")) {
 		args = append([]string{"sql-server"}, args...)
@@ -103,6 +242,23 @@ func runServer(args []string, fs filesys.Filesys) (*int, *sync.WaitGroup) {
 			}
 		}
 	}
+	// Dolt creates its Unix socket at the path that it's given, so the Postgres path is given unless the flag or the
+	// config file already chose one. An invalid config is reported once the server runs, so it's ignored here.
+	if serverArgs, err := (sqlserver.SqlServerCmd{}).ArgParser().Parse(args); serverMode && err == nil {
+		if serverConfig, err := sqlserver.GetServerConfig(fs, serverArgs); err == nil {
+			listenerCfg.socketPath = serverConfig.Socket()
+			if len(listenerCfg.socketPath) == 0 && len(socketDir) > 0 {
+				listenerCfg.socketPath = socketFilePath(socketDir, serverConfig.Port())
+				args = append(args, "--socket="+listenerCfg.socketPath)
+			}
+		}
+		if len(listenerCfg.socketPath) > 0 {
+			if err = prepareSocketFile(listenerCfg.socketPath); err != nil {
+				cli.PrintErrln(color.RedString("Failed to create the Unix socket: %v", err))
+				return intPointer(1), wg
+			}
+		}
+	}
 
 	if os.Getenv("DOLT_VERBOSE_ASSERT_TABLE_FILES_CLOSED") == "" {
 		nbs.TableIndexGCFinalizerWithStackTrace = false
@@ -303,22 +459,30 @@ func runServer(args []string, fs filesys.Filesys) (*int, *sync.WaitGroup) {
 	}
 
 	ctx, stop := context.WithCancel(ctx)
+	var serverConfig sqlserver.ServerConfig
 	if serverMode {
-		var serverConfig sqlserver.ServerConfig
 		// Grab the config so that we can pull the TLS key & cert. If there's an error then we can ignore it here, as
 		// it'll be caught when the server actually tries to run. We'll also use the config to determine whether we need to
 		// create a doltgres directory.
 		if sqlServerApr, err := cli.ParseArgs(sqlserver.SqlServerCmd{}.ArgParser(), args, nil); err == nil {
 			if serverConfig, err = sqlserver.GetServerConfig(dEnv.FS, sqlServerApr); err == nil {
 				// We throw an error if there's an issue with the TLS cert though
-				tlsConfig, err := sqlserver.LoadTLSConfig(serverConfig)
+				doltTLSConfig, err := sqlserver.LoadTLSConfig(serverConfig)
 				if err != nil {
 					stop()
 					cli.PrintErrln(err)
 					return intPointer(1), wg
 				}
-				if tlsConfig != nil && len(tlsConfig.Certificates) > 0 {
-					certificate = tlsConfig.Certificates[0]
+				// The SSL flags take precedence over the config's certificate
+				if doltTLSConfig != nil && len(doltTLSConfig.Certificates) > 0 && ssl.tlsConfig == nil {
+					if ssl.tlsConfig, err = newTLSConfig(doltTLSConfig.Certificates[0], caFile, tlsMinVersion); err != nil {
+						stop()
+						cli.PrintErrln(err)
+						return intPointer(1), wg
+					}
+				}
+				if serverConfig.RequireSecureTransport() {
+					ssl.mode = sslMode_Require
 				}
 			}
 		}
@@ -340,12 +504,21 @@ func runServer(args []string, fs filesys.Filesys) (*int, *sync.WaitGroup) {
 		}
 	}
 
+	// The engine's server creates the listener, so the listener finds its configuration using the server's port
+	if serverConfig != nil {
+		listenerCfg.ssl = ssl
+		listenerConfigs.Store(serverConfig.Port(), listenerCfg)
+	}
+
 	// We're now running the server, so we can increment the WaitGroup.
 	wg.Add(1)
 	res := new(int)
 	go func() {
 		defer wg.Done()
 		*res = doltCommand.Exec(ctx, "dolt", remainingArgs, dEnv, cliCtx)
+		if serverConfig != nil {
+			listenerConfigs.CompareAndDelete(serverConfig.Port(), listenerCfg)
+		}
 		stop()
 
 		if err = dbfactory.CloseAllLocalDatabases(); err != nil {
@@ -548,6 +721,23 @@ func getProfile(apr *argparser.ArgParseResults, profileName, profiles string) (r
 	}
 }
 
+// extractFlag removes the given flag and its value from the args, which may be given as either "--flag=value" or
+// "--flag value". The last occurrence of the flag determines the value.
+func extractFlag(args []string, flag string) (remainingArgs []string, value string, ok bool) {
+	remainingArgs = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == flag && i+1 < len(args) {
+			value, ok = args[i+1], true
+			i++
+		} else if strings.HasPrefix(args[i], flag+"=") {
+			value, ok = args[i][len(flag)+1:], true
+		} else {
+			remainingArgs = append(remainingArgs, args[i])
+		}
+	}
+	return remainingArgs, value, ok
+}
+
 func intPointer(val int) *int {
 	p := new(int)
 	*p = val
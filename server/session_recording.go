@@ -0,0 +1,105 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dolthub/dolt/go/cmd/dolt/cli"
+	"github.com/fatih/color"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+)
+
+// replayCommand is the command that replays session recordings against a running server, instead of starting one.
+const replayCommand = "replay"
+
+// replayUsage describes the arguments of the replay command.
+const replayUsage = "Usage: doltgresql replay [--host=<host>] [--port=<port>] <recording>..."
+
+// validateRecordDirectory returns an error if sessions can't be recorded to the given directory.
+func validateRecordDirectory(directory string) error {
+	info, err := os.Stat(directory)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", directory)
+	}
+	return nil
+}
+
+// startRecording begins recording the session of the connection with the given ID to the given directory, unless the
+// directory is empty. Each session is written to its own file, named after the time that the connection was accepted
+// and its connection ID. Returns nil if the session isn't recorded.
+func startRecording(recordDirectory string, connectionID uint32) *connection.Recorder {
+	if len(recordDirectory) == 0 {
+		return nil
+	}
+	name := fmt.Sprintf("session-%s-%d.jsonl", time.Now().UTC().Format("20060102T150405.000000"), connectionID)
+	recorder, err := connection.NewRecorder(filepath.Join(recordDirectory, name))
+	if err != nil {
+		fmt.Printf("Failed to record the session of connection %d:\n%v\n", connectionID, err)
+		return nil
+	}
+	return recorder
+}
+
+// runReplay replays each of the session recordings in the given args, in order, against the server at the given host
+// and port, which default to the server's own defaults. Each difference between the server's responses and a recording
+// is printed along with the recording's path. Returns the exit code, which is nonzero when a recording could not be
+// replayed or when any of the responses differ.
+func runReplay(args []string) int {
+	args, host, hasHost := extractFlag(args, "--host")
+	if !hasHost {
+		host = "localhost"
+	}
+	args, port, hasPort := extractFlag(args, "--port")
+	if !hasPort {
+		port = "5432"
+	}
+	if len(args) == 0 {
+		cli.PrintErrln(replayUsage)
+		return 1
+	}
+	address := net.JoinHostPort(host, port)
+	totalDifferences := 0
+	for _, path := range args {
+		recording, err := connection.ReadRecording(path)
+		if err != nil {
+			cli.PrintErrln(color.RedString("Failed to read the recording: %v", err))
+			return 1
+		}
+		differences, err := connection.ReplaySessions(address, recording)
+		for _, difference := range differences {
+			cli.Printf("%s: %s\n", path, difference.String())
+		}
+		totalDifferences += len(differences)
+		if err != nil {
+			cli.PrintErrln(color.RedString("Failed to replay %s: %v", path, err))
+			return 1
+		}
+	}
+	if totalDifferences > 0 {
+		cli.PrintErrln(color.RedString("%d responses differ from the recordings", totalDifferences))
+		return 1
+	}
+	cli.Printf("Replayed %d recordings without any differences\n", len(args))
+	return 0
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/dolthub/doltgresql/postgres/connection"
+)
+
+// sslMode determines whether clients may, or must, use SSL.
+type sslMode string
+
+const (
+	sslMode_Off     sslMode = "off"
+	sslMode_On      sslMode = "on"
+	sslMode_Require sslMode = "require"
+)
+
+// defaultTLSMinVersion is the oldest TLS version that clients may use, which is the same default as Postgres.
+const defaultTLSMinVersion = tls.VersionTLS12
+
+// sslConfig is the SSL configuration of a Listener.
+type sslConfig struct {
+	// tlsConfig is the configuration of SSL connections, or nil when the listener does not support SSL.
+	tlsConfig *tls.Config
+	// mode determines whether clients may use SSL. When it's required, it only applies to TCP connections, as Unix
+	// socket connections never use SSL.
+	mode sslMode
+}
+
+// tlsVersions are the TLS versions that may be given as the minimum version, named as in ssl_min_protocol_version.
+var tlsVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": tls.VersionTLS13,
+}
+
+// parseSSLMode parses the given SSL mode.
+func parseSSLMode(mode string) (sslMode, error) {
+	switch sslMode(strings.ToLower(mode)) {
+	case sslMode_Off:
+		return sslMode_Off, nil
+	case sslMode_On:
+		return sslMode_On, nil
+	case sslMode_Require:
+		return sslMode_Require, nil
+	default:
+		return "", fmt.Errorf("invalid SSL mode: %s", mode)
+	}
+}
+
+// parseTLSVersion parses the given TLS version, such as "TLSv1.2".
+func parseTLSVersion(version string) (uint16, error) {
+	if tlsVersion, ok := tlsVersions[strings.ToLower(version)]; ok {
+		return tlsVersion, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version: %s", version)
+}
+
+// newTLSConfig returns the configuration of SSL connections for the given server certificate. When a CA file is given,
+// clients are asked for a certificate, which must be signed by one of the file's certificates if one is presented.
+// Such certificates are used by cert authentication.
+func newTLSConfig(certificate tls.Certificate, caFile string, minVersion uint16) (*tls.Config, error) {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   minVersion,
+	}
+	if len(caFile) > 0 {
+		caCertificates, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("no certificates were found in %s", caFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// supportsSSL returns whether clients may upgrade their connections to SSL.
+func (c sslConfig) supportsSSL() bool {
+	return c.tlsConfig != nil && c.mode != sslMode_Off
+}
+
+// certificate returns the certificate that is presented to clients, which is empty when SSL is not supported.
+func (c sslConfig) certificate() tls.Certificate {
+	if c.tlsConfig == nil || len(c.tlsConfig.Certificates) == 0 {
+		return tls.Certificate{}
+	}
+	return c.tlsConfig.Certificates[0]
+}
+
+// requiresSSL returns whether the given connection must have been upgraded to SSL before its StartupMessage.
+func (c sslConfig) requiresSSL(conn net.Conn) bool {
+	if c.mode != sslMode_Require || conn.RemoteAddr().Network() == "unix" {
+		return false
+	}
+	_, isTLS := asTLSConn(conn)
+	return !isTLS
+}
+
+// asTLSConn returns the SSL connection of the given connection, and whether the connection was upgraded to SSL.
+func asTLSConn(conn net.Conn) (*tls.Conn, bool) {
+	tlsConn, ok := connection.Unwrap(conn).(*tls.Conn)
+	return tlsConn, ok
+}
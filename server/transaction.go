@@ -0,0 +1,125 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/dolthub/vitess/go/vt/sqlparser"
+
+	"github.com/dolthub/doltgresql/postgres/messages"
+)
+
+// errTransactionAborted is returned for every statement that is run within a failed transaction block, other than the
+// statements that end the transaction block.
+var errTransactionAborted = sqlStateError{
+	Code:    "25P02",
+	Message: "current transaction is aborted, commands ignored until end of transaction block",
+}
+
+// transactionState tracks the status of a connection's transaction block, which is reported to the client by every
+// ReadyForQuery message. A transaction block begins with BEGIN, and fails once any of its statements returns an error.
+// A failed transaction block rejects every statement until it has been rolled back. The connection's pending
+// notifications are committed or discarded along with the transaction. A query with multiple statements runs within
+// an implicit transaction, which isn't reported to the client, and ends along with the query.
+type transactionState struct {
+	indicator     messages.ReadyForQueryTransactionIndicator
+	implicit      bool
+	notifications *notificationSession
+}
+
+// newTransactionState returns the state of a connection that is not within a transaction block.
+func newTransactionState(notifications *notificationSession) *transactionState {
+	return &transactionState{
+		indicator:     messages.ReadyForQueryTransactionIndicator_Idle,
+		notifications: notifications,
+	}
+}
+
+// Indicator returns the indicator that should be sent with the ReadyForQuery message.
+func (ts *transactionState) Indicator() messages.ReadyForQueryTransactionIndicator {
+	return ts.indicator
+}
+
+// Autocommit returns whether statements commit on their own, which is the case when they're neither within a
+// transaction block nor an implicit transaction.
+func (ts *transactionState) Autocommit() bool {
+	return ts.indicator == messages.ReadyForQueryTransactionIndicator_Idle && !ts.implicit
+}
+
+// Implicit returns whether statements are running within an implicit transaction.
+func (ts *transactionState) Implicit() bool {
+	return ts.implicit
+}
+
+// BeginImplicit updates the state once an implicit transaction has begun.
+func (ts *transactionState) BeginImplicit() {
+	ts.implicit = true
+}
+
+// Check returns the query that should be run in place of the given query. Queries are returned as-is, unless the
+// transaction block has failed, in which case only the queries that roll back the transaction are allowed. As in
+// Postgres, committing a failed transaction block rolls it back instead.
+func (ts *transactionState) Check(query ConvertedQuery) (ConvertedQuery, error) {
+	if ts.indicator != messages.ReadyForQueryTransactionIndicator_FailedTransactionBlock {
+		return query, nil
+	}
+	switch query.AST.(type) {
+	case *sqlparser.Rollback, *sqlparser.RollbackSavepoint:
+		return query, nil
+	case *sqlparser.Commit:
+		return ConvertedQuery{
+			String: "ROLLBACK",
+			AST:    &sqlparser.Rollback{},
+		}, nil
+	default:
+		return ConvertedQuery{}, errTransactionAborted
+	}
+}
+
+// Executed updates the state once the given query has run successfully. Within an implicit transaction, BEGIN turns the
+// implicit transaction into a transaction block, while COMMIT and ROLLBACK end it.
+func (ts *transactionState) Executed(query ConvertedQuery) {
+	switch query.AST.(type) {
+	case *sqlparser.Begin:
+		ts.indicator = messages.ReadyForQueryTransactionIndicator_TransactionBlock
+		ts.implicit = false
+	case *sqlparser.Commit:
+		ts.indicator = messages.ReadyForQueryTransactionIndicator_Idle
+		ts.implicit = false
+		ts.notifications.Commit()
+	case *sqlparser.Rollback:
+		ts.indicator = messages.ReadyForQueryTransactionIndicator_Idle
+		ts.implicit = false
+		ts.notifications.Rollback()
+	case *sqlparser.RollbackSavepoint:
+		// Rolling back to a savepoint recovers a failed transaction block
+		ts.indicator = messages.ReadyForQueryTransactionIndicator_TransactionBlock
+	default:
+		// Statements outside of a transaction block commit on their own
+		if ts.Autocommit() {
+			ts.notifications.Commit()
+		}
+	}
+}
+
+// Failed updates the state once a statement has returned an error. Errors outside of a transaction block do not
+// affect the state, other than discarding the statement's pending notifications.
+func (ts *transactionState) Failed() {
+	switch ts.indicator {
+	case messages.ReadyForQueryTransactionIndicator_Idle:
+		ts.notifications.Rollback()
+	case messages.ReadyForQueryTransactionIndicator_TransactionBlock:
+		ts.indicator = messages.ReadyForQueryTransactionIndicator_FailedTransactionBlock
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultSocketDirectory is the directory that the Unix socket is created in, which is the same default that libpq
+	// uses when a host isn't given.
+	defaultSocketDirectory = "/tmp"
+	// defaultSocketPermissions are the access permissions of the Unix socket, which allow anyone to connect.
+	defaultSocketPermissions = os.FileMode(0777)
+)
+
+// socketFilePath returns the path of the Unix socket within the given directory for the given port. Like Postgres,
+// this is named after the port, so that clients can find it from the port alone.
+func socketFilePath(directory string, port int) string {
+	return filepath.Join(directory, fmt.Sprintf(".s.PGSQL.%d", port))
+}
+
+// parseSocketPermissions parses the given permissions, which are written in octal as with chmod.
+func parseSocketPermissions(permissions string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(permissions, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket permissions: %s", permissions)
+	}
+	return os.FileMode(mode), nil
+}
+
+// prepareSocketFile ensures that the Unix socket can be created at the given path. A socket that was left behind by a
+// server that has since stopped is removed, while a socket that is still accepting connections returns an error.
+func prepareSocketFile(path string) error {
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return fmt.Errorf("socket directory does not exist: %s", filepath.Dir(path))
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("a file that is not a socket already exists at %s", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("another server is already listening on %s", path)
+	}
+	return os.Remove(path)
+}
+
+// setSocketPermissions applies the given permissions to the Unix socket at the given path, which must have already
+// been created. Nothing is changed when the path is empty.
+func setSocketPermissions(socketPath string, permissions os.FileMode) error {
+	if len(socketPath) == 0 {
+		return nil
+	}
+	if err := os.Chmod(socketPath, permissions); err != nil {
+		return fmt.Errorf("unable to set the permissions of the Unix socket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package _go
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFrom(t *testing.T) {
+	ctx, conn, serverClosed := CreateServer(t, "postgres")
+	defer func() {
+		conn.Close(ctx)
+		serverClosed.Wait()
+	}()
+	_, err := conn.Exec(ctx, "CREATE TABLE test (pk BIGINT PRIMARY KEY, v1 VARCHAR(255), v2 BOOLEAN);")
+	require.NoError(t, err)
+	pgConn := conn.PgConn()
+
+	// The text format uses tabs and \N by default, with backslash escapes
+	tag, err := pgConn.CopyFrom(ctx, strings.NewReader("1\tfirst\tt\n2\t\\N\tf\n3\ttab\\there\\\\\t\\N\n"),
+		"COPY test FROM STDIN;")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 3", tag.String())
+
+	// The CSV format uses quotes, and only an unquoted empty value is NULL
+	tag, err = pgConn.CopyFrom(ctx, strings.NewReader("pk,v1\n4,\"quoted, \"\"value\"\"\"\n5,\n6,\"\"\n7,\"two\nlines\"\n"),
+		"COPY test (pk, v1) FROM STDIN WITH (FORMAT csv, HEADER);")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 4", tag.String())
+
+	// Options may also be given without parentheses
+	tag, err = pgConn.CopyFrom(ctx, strings.NewReader("8|'x|y'|true\n9|NULL|false\n\\.\n"),
+		"COPY test FROM STDIN CSV DELIMITER '|' NULL 'NULL' QUOTE '''';")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 2", tag.String())
+
+	// A failure inserts none of the rows, and the connection remains usable
+	_, err = pgConn.CopyFrom(ctx, strings.NewReader("10\tvalid\tt\n11\tmissing\n"), "COPY test FROM STDIN;")
+	RequireSQLState(t, "22P04", err)
+	_, err = pgConn.CopyFrom(ctx, strings.NewReader("1\tduplicate\tt\n"), "COPY test FROM STDIN;")
+	require.Error(t, err)
+	_, err = pgConn.CopyFrom(ctx, strings.NewReader("10\tvalid\tt\n"), "COPY test FROM STDIN WITH (QUOTE '\"');")
+	RequireSQLState(t, "0A000", err)
+	// COPY streams its rows through the simple query protocol, so it can't be prepared
+	_, err = conn.Prepare(ctx, "", "COPY test FROM STDIN;")
+	RequireSQLState(t, "0A000", err)
+	_, err = conn.Prepare(ctx, "", "COPY test TO STDOUT;")
+	RequireSQLState(t, "0A000", err)
+	// Rows are inserted in batches, so this fails after some of the rows have already been inserted
+	var data strings.Builder
+	for i := 100; i < 2100; i++ {
+		fmt.Fprintf(&data, "%d\tbatched\tf\n", i)
+	}
+	data.WriteString("1\tduplicate\tt\n")
+	_, err = pgConn.CopyFrom(ctx, strings.NewReader(data.String()), "COPY test FROM STDIN;")
+	require.Error(t, err)
+
+	rows, err := conn.Query(ctx, "SELECT pk, v1 FROM test ORDER BY pk;")
+	require.NoError(t, err)
+	readRows := ReadRows(t, rows)
+	rows.Close()
+	assert.Equal(t, []sql.Row{
+		{int64(1), "first"},
+		{int64(2), nil},
+		{int64(3), "tab\there\\"},
+		{int64(4), `quoted, "value"`},
+		{int64(5), nil},
+		{int64(6), ""},
+		{int64(7), "two\nlines"},
+		{int64(8), "x|y"},
+		{int64(9), nil},
+	}, readRows)
+
+	// Booleans use the same representations as they do in queries
+	rows, err = conn.Query(ctx, "SELECT pk FROM test WHERE v2 = true ORDER BY pk;")
+	require.NoError(t, err)
+	readRows = ReadRows(t, rows)
+	rows.Close()
+	assert.Equal(t, []sql.Row{{int64(1)}, {int64(8)}}, readRows)
+	rows, err = conn.Query(ctx, "SELECT pk FROM test WHERE v2 = false ORDER BY pk;")
+	require.NoError(t, err)
+	readRows = ReadRows(t, rows)
+	rows.Close()
+	assert.Equal(t, []sql.Row{{int64(2)}, {int64(9)}}, readRows)
+}
+
+func TestCopyTo(t *testing.T) {
+	ctx, conn, serverClosed := CreateServer(t, "postgres")
+	defer func() {
+		conn.Close(ctx)
+		serverClosed.Wait()
+	}()
+	_, err := conn.Exec(ctx, "CREATE TABLE test (pk BIGINT PRIMARY KEY, v1 VARCHAR(255), v2 INTEGER);")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, `INSERT INTO test VALUES (1, 'first', 10), (2, NULL, 20), (3, E'tab\there\\', NULL), (4, 'quoted, "value"', 40), (5, '', 50);`)
+	require.NoError(t, err)
+	pgConn := conn.PgConn()
+
+	var buf bytes.Buffer
+	tag, err := pgConn.CopyTo(ctx, &buf, "COPY test TO STDOUT;")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 5", tag.String())
+	assert.Equal(t, "1\tfirst\t10\n2\t\\N\t20\n3\ttab\\there\\\\\t\\N\n4\tquoted, \"value\"\t40\n5\t\t50\n", buf.String())
+
+	buf.Reset()
+	tag, err = pgConn.CopyTo(ctx, &buf, "COPY test (v1, pk) TO STDOUT WITH (FORMAT csv, HEADER);")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 5", tag.String())
+	assert.Equal(t, "v1,pk\nfirst,1\n,2\ntab\there\\,3\n\"quoted, \"\"value\"\"\",4\n\"\",5\n", buf.String())
+
+	buf.Reset()
+	tag, err = pgConn.CopyTo(ctx, &buf, "COPY (SELECT pk, v2 FROM test WHERE v2 > 15 ORDER BY pk) TO STDOUT DELIMITER '|' NULL 'null';")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 3", tag.String())
+	assert.Equal(t, "2|20\n4|40\n5|50\n", buf.String())
+
+	// The binary format contains the signature, the flags and header extension, each row's values, and the trailer
+	buf.Reset()
+	tag, err = pgConn.CopyTo(ctx, &buf, "COPY (SELECT pk, v1 FROM test WHERE pk = 2) TO STDOUT WITH (FORMAT binary);")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 1", tag.String())
+	expected := append([]byte("PGCOPY\n\xff\r\n\x00"), 0, 0, 0, 0, 0, 0, 0, 0)
+	expected = append(expected, 0, 2, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 2, 0xff, 0xff, 0xff, 0xff)
+	expected = append(expected, 0xff, 0xff)
+	assert.Equal(t, expected, buf.Bytes())
+
+	_, err = pgConn.CopyTo(ctx, &buf, "COPY nonexistent TO STDOUT;")
+	require.Error(t, err)
+	_, err = pgConn.CopyTo(ctx, &buf, "COPY test TO STDOUT WITH (FORMAT binary, HEADER);")
+	RequireSQLState(t, "42601", err)
+}
+
+func TestCopyBinary(t *testing.T) {
+	ctx, conn, serverClosed := CreateServer(t, "postgres")
+	defer func() {
+		conn.Close(ctx)
+		serverClosed.Wait()
+	}()
+	_, err := conn.Exec(ctx, "CREATE TABLE test (pk BIGINT PRIMARY KEY, v1 VARCHAR(255), v2 INTEGER, v3 DOUBLE PRECISION, v4 DATE, v5 BOOLEAN);")
+	require.NoError(t, err)
+
+	// pgx only uses the binary format for CopyFrom
+	date := time.Date(2023, 10, 17, 0, 0, 0, 0, time.UTC)
+	count, err := conn.CopyFrom(ctx, pgx.Identifier{"test"}, []string{"pk", "v1", "v2", "v3", "v4", "v5"}, pgx.CopyFromRows([][]any{
+		{int64(1), "first", int32(10), 1.5, date, true},
+		{int64(2), nil, nil, nil, nil, nil},
+		{int64(3), "tab\there", int32(-30), -2.25, date.AddDate(0, 0, 1), false},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = conn.CopyFrom(ctx, pgx.Identifier{"test"}, []string{"v1", "pk"}, pgx.CopyFromRows([][]any{
+		{"columns", int64(4)},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	rows, err := conn.Query(ctx, "SELECT * FROM test ORDER BY pk;")
+	require.NoError(t, err)
+	readRows := ReadRows(t, rows)
+	rows.Close()
+	assert.Equal(t, []sql.Row{
+		{int64(1), "first", int64(10), 1.5, "2023-10-17 00:00:00", true},
+		{int64(2), nil, nil, nil, nil, nil},
+		{int64(3), "tab\there", int64(-30), -2.25, "2023-10-18 00:00:00", false},
+		{int64(4), "columns", nil, nil, nil, nil},
+	}, readRows)
+
+	// The binary data that COPY TO produces may be copied back in
+	var buf bytes.Buffer
+	_, err = conn.PgConn().CopyTo(ctx, &buf, "COPY (SELECT pk + 10, v1, v2, v3, v4, v5 FROM test) TO STDOUT WITH (FORMAT binary);")
+	require.NoError(t, err)
+	tag, err := conn.PgConn().CopyFrom(ctx, &buf, "COPY test FROM STDIN WITH (FORMAT binary);")
+	require.NoError(t, err)
+	assert.Equal(t, "COPY 4", tag.String())
+
+	_, err = conn.PgConn().CopyFrom(ctx, strings.NewReader("1\tnot binary\n"), "COPY test FROM STDIN WITH (FORMAT binary);")
+	RequireSQLState(t, "22P04", err)
+	data := append([]byte("PGCOPY\n\xff\r\n\x00"), 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 99, 0xff, 0xff)
+	_, err = conn.PgConn().CopyFrom(ctx, bytes.NewReader(data), "COPY test FROM STDIN WITH (FORMAT binary);")
+	RequireSQLState(t, "22P04", err)
+	data = append([]byte("PGCOPY\n\xff\r\n\x00"), 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 99, 0xff, 0xff)
+	_, err = conn.PgConn().CopyFrom(ctx, bytes.NewReader(data), "COPY test (pk) FROM STDIN WITH (FORMAT binary);")
+	require.NoError(t, err)
+
+	rows, err = conn.Query(ctx, "SELECT pk, v5 FROM test ORDER BY pk;")
+	require.NoError(t, err)
+	readRows = ReadRows(t, rows)
+	rows.Close()
+	assert.Equal(t, []sql.Row{{int64(1), true}, {int64(2), nil}, {int64(3), false}, {int64(4), nil}, {int64(11), true},
+		{int64(12), nil}, {int64(13), false}, {int64(14), nil}, {int64(99), nil}}, readRows)
+}
@@ -17,17 +17,21 @@ package _go
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net"
 	"os"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/dolthub/go-mysql-server/server"
 	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/dolthub/vitess/go/mysql"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -59,6 +63,13 @@ type ScriptTestAssertion struct {
 	Expected    []sql.Row
 	ExpectedErr bool
 
+	// ExpectedErrCode is the SQLSTATE code of the expected error. Setting this also sets ExpectedErr.
+	ExpectedErrCode string
+
+	// BindVars are the values that are bound to the query's parameters ($1, $2, etc.), in order. Queries with bind
+	// variables are always run as prepared statements.
+	BindVars []any
+
 	// SkipResultsCheck is used to skip assertions on the expected rows returned from a query. For now, this is
 	// included as some messages do not have a full logical implementation. Skipping the results check allows us to
 	// force the test client to not send of those messages.
@@ -95,15 +106,18 @@ func RunScript(t *testing.T, script ScriptTest) {
 				}
 				// If we're skipping the results check, then we call Execute, as it uses a simplified message model.
 				// The more complicated model is only partially implemented, and therefore won't work for all queries.
-				if assertion.SkipResultsCheck || assertion.ExpectedErr {
-					_, err := conn.Exec(ctx, assertion.Query)
+				if len(assertion.ExpectedErrCode) > 0 {
+					_, err := conn.Exec(ctx, assertion.Query, assertion.BindVars...)
+					RequireSQLState(t, assertion.ExpectedErrCode, err)
+				} else if assertion.SkipResultsCheck || assertion.ExpectedErr {
+					_, err := conn.Exec(ctx, assertion.Query, assertion.BindVars...)
 					if assertion.ExpectedErr {
 						require.Error(t, err)
 					} else {
 						require.NoError(t, err)
 					}
 				} else {
-					rows, err := conn.Query(ctx, assertion.Query)
+					rows, err := conn.Query(ctx, assertion.Query, assertion.BindVars...)
 					require.NoError(t, err)
 					defer rows.Close()
 					assert.Equal(t, NormalizeRows(assertion.Expected), ReadRows(t, rows))
@@ -176,6 +190,61 @@ func CreateServer(t *testing.T, database string) (context.Context, *pgx.Conn, *s
 	return ctx, conn, serverClosed
 }
 
+// RunServer starts a server with the given args, returning the port that the server is listening on. The server will
+// close after two connections, and is closed once the test finishes if it's still running afterward.
+func RunServer(t *testing.T, args ...string) int {
+	port := GetUnusedPort(t)
+	listeners := make(chan server.ProtocolListener, 1)
+	server.DefaultProtocolListenerFunc = func(cfg mysql.ListenerConfig) (server.ProtocolListener, error) {
+		listener, err := dserver.NewLimitedListener(cfg)
+		if err == nil {
+			listeners <- listener
+		}
+		return listener, err
+	}
+	code, serverClosed := dserver.RunInMemory(append([]string{fmt.Sprintf("--port=%d", port), "--host=127.0.0.1"}, args...))
+	require.Equal(t, 0, *code)
+	closed := make(chan struct{})
+	go func() {
+		serverClosed.Wait()
+		close(closed)
+	}()
+	t.Cleanup(func() {
+		// The server normally closes itself, so it's only closed here when the test did not make both connections
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			select {
+			case listener := <-listeners:
+				listener.Close()
+				<-closed
+			case <-closed:
+			}
+		}
+	})
+	return port
+}
+
+// ConnectWithRetry connects using the given connection string. The connection attempt may be made before the server
+// has grabbed the port, so refused connections are retried a few times. Other errors are returned immediately, so that
+// each connection that the server accepts corresponds to a single attempt.
+func ConnectWithRetry(ctx context.Context, connString string) (conn *pgx.Conn, err error) {
+	for i := 0; i < 3; i++ {
+		if conn, err = pgx.Connect(ctx, connString); err == nil || !errors.Is(err, syscall.ECONNREFUSED) {
+			return conn, err
+		}
+		time.Sleep(time.Second)
+	}
+	return nil, err
+}
+
+// RequireSQLState requires that the given error was returned from the server with the given SQLSTATE code.
+func RequireSQLState(t *testing.T, code string, err error) {
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr), "expected an error from the server, but received: %v", err)
+	require.Equal(t, code, pgErr.Code, pgErr.Message)
+}
+
 // ReadRows reads all of the given rows into a slice. This also normalizes all of the rows. Does not call Close() on the rows.
 func ReadRows(t *testing.T, rows pgx.Rows) []sql.Row {
 	var slice []sql.Row
@@ -0,0 +1,97 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package _go
+
+import (
+	"testing"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipleStatements(t *testing.T) {
+	ctx, conn, serverClosed := CreateServer(t, "postgres")
+	defer func() {
+		conn.Close(ctx)
+		serverClosed.Wait()
+	}()
+
+	frontend := conn.PgConn().Frontend()
+	for _, step := range []struct {
+		query    string
+		expected []string
+	}{
+		{
+			query: "CREATE TABLE test (pk BIGINT PRIMARY KEY); INSERT INTO test VALUES (1), (2);\nSELECT * FROM test ORDER BY pk;",
+			expected: []string{
+				"SELECT 0",
+				"INSERT 0 2",
+				"RowDescription", "DataRow 1-2", "SELECT 2",
+				"ReadyForQuery I",
+			},
+		},
+		{
+			// The statements before the error are rolled back along with the implicit transaction
+			query:    "INSERT INTO test VALUES (3); INSERT INTO test VALUES (1); INSERT INTO test VALUES (4);",
+			expected: []string{"INSERT 0 1", "ErrorResponse 23505", "ReadyForQuery I"},
+		},
+		{
+			query:    "SELECT * FROM test ORDER BY pk; SELECT * FROM test WHERE pk > 1;",
+			expected: []string{"RowDescription", "DataRow 1-2", "SELECT 2", "RowDescription", "DataRow 2-2", "SELECT 1", "ReadyForQuery I"},
+		},
+		{
+			// COMMIT ends the implicit transaction, so only the statements after it are rolled back
+			query:    "INSERT INTO test VALUES (5); COMMIT; INSERT INTO test VALUES (6); SELECT * FROM nonexistent;",
+			expected: []string{"INSERT 0 1", "SELECT 0", "INSERT 0 1", "ErrorResponse 42P01", "ReadyForQuery I"},
+		},
+		{
+			// BEGIN turns the implicit transaction into a transaction block, which includes the statements before it
+			query:    "INSERT INTO test VALUES (7); BEGIN; INSERT INTO test VALUES (8);",
+			expected: []string{"INSERT 0 1", "SELECT 0", "INSERT 0 1", "ReadyForQuery T"},
+		},
+		{
+			query:    "ROLLBACK;",
+			expected: []string{"SELECT 0", "ReadyForQuery I"},
+		},
+		{
+			// Statements within a transaction block don't start an implicit transaction, and fail the block on error
+			query:    "BEGIN; INSERT INTO test VALUES (9);",
+			expected: []string{"SELECT 0", "INSERT 0 1", "ReadyForQuery T"},
+		},
+		{
+			query:    "INSERT INTO test VALUES (10); INSERT INTO test VALUES (9); INSERT INTO test VALUES (11);",
+			expected: []string{"INSERT 0 1", "ErrorResponse 23505", "ReadyForQuery E"},
+		},
+		{
+			query:    "ROLLBACK; SELECT * FROM test ORDER BY pk;",
+			expected: []string{"SELECT 0", "RowDescription", "DataRow 1-5", "SELECT 3", "ReadyForQuery I"},
+		},
+		{
+			query:    ";",
+			expected: []string{"EmptyQueryResponse", "ReadyForQuery I"},
+		},
+	} {
+		frontend.Send(&pgproto3.Query{String: step.query})
+		require.NoError(t, frontend.Flush())
+		assert.Equal(t, step.expected, receiveMessages(t, frontend), step.query)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT * FROM test ORDER BY pk;")
+	require.NoError(t, err)
+	defer rows.Close()
+	assert.Equal(t, NormalizeRows([]sql.Row{{1}, {2}, {5}}), ReadRows(t, rows))
+}
@@ -15,6 +15,8 @@
 package _go
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/dolthub/go-mysql-server/sql"
@@ -71,6 +73,29 @@ func TestSmokeTests(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Messages larger than the read buffer",
+			SetUpScript: []string{
+				"CREATE TABLE test (pk BIGINT PRIMARY KEY, v1 VARCHAR(16000));",
+				fmt.Sprintf("INSERT INTO test VALUES (1, '%s');", strings.Repeat("abcdefghij", 500)),
+				fmt.Sprintf("INSERT INTO test VALUES (2, '%s');", strings.Repeat("0123456789", 1500)),
+			},
+			Assertions: []ScriptTestAssertion{
+				{
+					Query: "SELECT pk, length(v1) FROM test ORDER BY pk;",
+					Expected: []sql.Row{
+						{1, 5000},
+						{2, 15000},
+					},
+				},
+				{
+					Query: fmt.Sprintf("SELECT pk FROM test WHERE v1 = '%s';", strings.Repeat("abcdefghij", 500)),
+					Expected: []sql.Row{
+						{1},
+					},
+				},
+			},
+		},
 		{
 			Name: "Unsupported MySQL statements",
 			Assertions: []ScriptTestAssertion{
@@ -0,0 +1,98 @@
+// Copyright 2023 Dolthub, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package _go
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dolthub/go-mysql-server/sql"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionStatus(t *testing.T) {
+	ctx, conn, serverClosed := CreateServer(t, "postgres")
+	defer func() {
+		conn.Close(ctx)
+		serverClosed.Wait()
+	}()
+	_, err := conn.Exec(ctx, "CREATE TABLE test (pk BIGINT PRIMARY KEY);")
+	require.NoError(t, err)
+
+	frontend := conn.PgConn().Frontend()
+	for _, step := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: "SELECT * FROM nonexistent;", expected: []string{"ErrorResponse 42P01", "ReadyForQuery I"}},
+		{query: "BEGIN;", expected: []string{"ReadyForQuery T"}},
+		{query: "INSERT INTO test VALUES (1);", expected: []string{"ReadyForQuery T"}},
+		{query: "INSERT INTO test VALUES (1);", expected: []string{"ErrorResponse 23505", "ReadyForQuery E"}},
+		{query: "SELECT * FROM test;", expected: []string{"ErrorResponse 25P02", "ReadyForQuery E"}},
+		{query: "INSERT INTO test VALUES (2);", expected: []string{"ErrorResponse 25P02", "ReadyForQuery E"}},
+		{query: "ROLLBACK;", expected: []string{"ReadyForQuery I"}},
+		{query: "START TRANSACTION;", expected: []string{"ReadyForQuery T"}},
+		{query: "INSERT INTO test VALUES (3);", expected: []string{"ReadyForQuery T"}},
+		{query: "COMMIT;", expected: []string{"ReadyForQuery I"}},
+		{query: "BEGIN;", expected: []string{"ReadyForQuery T"}},
+		{query: "INSERT INTO test VALUES (4);", expected: []string{"ReadyForQuery T"}},
+		{query: "SELECT * FROM nonexistent;", expected: []string{"ErrorResponse 42P01", "ReadyForQuery E"}},
+		// Committing a failed transaction rolls it back
+		{query: "COMMIT;", expected: []string{"ReadyForQuery I"}},
+	} {
+		frontend.Send(&pgproto3.Query{String: step.query})
+		require.NoError(t, frontend.Flush())
+		assert.Equal(t, step.expected, receiveStatus(t, frontend), step.query)
+	}
+
+	// The extended query protocol rejects statements at every step of a failed transaction
+	frontend.Send(&pgproto3.Query{String: "BEGIN;"})
+	frontend.Send(&pgproto3.Parse{Query: "SELECT * FROM nonexistent;"})
+	frontend.Send(&pgproto3.Bind{})
+	frontend.Send(&pgproto3.Execute{})
+	frontend.Send(&pgproto3.Sync{})
+	require.NoError(t, frontend.Flush())
+	assert.Equal(t, []string{"ReadyForQuery T"}, receiveStatus(t, frontend))
+	assert.Equal(t, []string{"ErrorResponse 42P01", "ReadyForQuery E"}, receiveStatus(t, frontend))
+	frontend.Send(&pgproto3.Parse{Query: "SELECT * FROM test;"})
+	frontend.Send(&pgproto3.Sync{})
+	require.NoError(t, frontend.Flush())
+	assert.Equal(t, []string{"ErrorResponse 25P02", "ReadyForQuery E"}, receiveStatus(t, frontend))
+	frontend.Send(&pgproto3.Parse{Query: "ROLLBACK;"})
+	frontend.Send(&pgproto3.Bind{})
+	frontend.Send(&pgproto3.Execute{})
+	frontend.Send(&pgproto3.Sync{})
+	require.NoError(t, frontend.Flush())
+	assert.Equal(t, []string{"ReadyForQuery I"}, receiveStatus(t, frontend))
+
+	rows, err := conn.Query(ctx, "SELECT * FROM test ORDER BY pk;")
+	require.NoError(t, err)
+	defer rows.Close()
+	assert.Equal(t, NormalizeRows([]sql.Row{{3}}), ReadRows(t, rows))
+}
+
+// receiveStatus reads the messages that the server sends until it is ready for the next query, returning only the
+// descriptions of the errors and the ReadyForQuery message.
+func receiveStatus(t *testing.T, frontend *pgproto3.Frontend) []string {
+	var descriptions []string
+	for _, description := range receiveMessages(t, frontend) {
+		if strings.HasPrefix(description, "ErrorResponse") || strings.HasPrefix(description, "ReadyForQuery") {
+			descriptions = append(descriptions, description)
+		}
+	}
+	return descriptions
+}